@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// accessLevels は保護ブランチのアクセスレベル名と値の対応
+var accessLevels = map[string]gitlab.AccessLevelValue{
+	"no_access":  gitlab.NoPermissions,
+	"developer":  gitlab.DeveloperPermissions,
+	"maintainer": gitlab.MaintainerPermissions,
+	"admin":      gitlab.AdminPermissions,
+}
+
+func registerBranchTools(s *server.MCPServer) {
+	// ブランチ詳細取得
+	s.AddTool(
+		mcp.NewTool("get_branch",
+			mcp.WithDescription("Get a branch with its latest commit and how many commits it is ahead of/behind the default branch"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Branch name"),
+			),
+			mcp.WithString("compare_to",
+				mcp.Description("Branch to count ahead/behind against (default: default branch)"),
+			),
+		),
+		handleGetBranch,
+	)
+
+	// ブランチ削除
+	s.AddTool(
+		mcp.NewTool("delete_branch",
+			mcp.WithDescription("Delete a branch from a GitLab project"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Branch name"),
+			),
+		),
+		handleDeleteBranch,
+	)
+
+	// マージ済みブランチの一括削除
+	s.AddTool(
+		mcp.NewTool("delete_merged_branches",
+			mcp.WithDescription("Delete branches that are merged into the default branch. Protected and default branches are never deleted. Branches that were never the source of a merged merge request (e.g. created from the default branch without commits of their own) are skipped unless include_without_merge_request is true. Runs as a dry run listing the candidates unless dry_run is false"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Only consider branches whose name matches this search (e.g., 'feature/', '^fix-')"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Only list the branches that would be deleted (default: true)"),
+			),
+			mcp.WithBoolean("include_without_merge_request",
+				mcp.Description("Also delete merged branches that were never the source of a merged merge request, such as branches created without commits of their own or merged by direct push (default: false)"),
+			),
+		),
+		handleDeleteMergedBranches,
+	)
+
+	// ブランチ保護
+	s.AddTool(
+		mcp.NewTool("protect_branch",
+			mcp.WithDescription("Protect a branch or wildcard pattern with push/merge access levels"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Branch name or wildcard pattern (e.g., 'release/*')"),
+			),
+			mcp.WithString("push_access_level",
+				mcp.Description("Who can push: no_access, developer, maintainer, admin (default: maintainer)"),
+			),
+			mcp.WithString("merge_access_level",
+				mcp.Description("Who can merge: no_access, developer, maintainer, admin (default: maintainer)"),
+			),
+			mcp.WithString("unprotect_access_level",
+				mcp.Description("Who can unprotect: developer, maintainer, admin (default: maintainer)"),
+			),
+			mcp.WithBoolean("allow_force_push",
+				mcp.Description("Allow force push for users who can push (default: false)"),
+			),
+			mcp.WithBoolean("code_owner_approval_required",
+				mcp.Description("Require code owner approval for pushes and merges (Premium)"),
+			),
+		),
+		handleProtectBranch,
+	)
+
+	// ブランチ保護解除
+	s.AddTool(
+		mcp.NewTool("unprotect_branch",
+			mcp.WithDescription("Remove protection from a branch or wildcard pattern"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Protected branch name or wildcard pattern"),
+			),
+		),
+		handleUnprotectBranch,
+	)
+}
+
+func handleGetBranch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	branchName, ok := args["branch"].(string)
+	if !ok || branchName == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	branch, _, err := gitlabClient.Branches.GetBranch(projectID, branchName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get branch: %v", err)), nil
+	}
+
+	compareTo := getString(args, "compare_to", "")
+	if compareTo == "" {
+		project, _, err := gitlabClient.Projects.GetProject(projectID, nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get project: %v", err)), nil
+		}
+		compareTo = project.DefaultBranch
+	}
+
+	result := map[string]interface{}{
+		"name":       branch.Name,
+		"protected":  branch.Protected,
+		"merged":     branch.Merged,
+		"default":    branch.Default,
+		"can_push":   branch.CanPush,
+		"web_url":    branch.WebURL,
+		"compare_to": compareTo,
+	}
+	if branch.Commit != nil {
+		result["commit"] = commitSummary(branch.Commit)
+	}
+
+	if branch.Name != compareTo {
+		// compare_to...branch と branch...compare_to のコミット数がそれぞれ ahead / behind
+		ahead, _, err := gitlabClient.Repositories.Compare(projectID, &gitlab.CompareOptions{
+			From: gitlab.Ptr(compareTo),
+			To:   gitlab.Ptr(branch.Name),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to compare branches: %v", err)), nil
+		}
+		behind, _, err := gitlabClient.Repositories.Compare(projectID, &gitlab.CompareOptions{
+			From: gitlab.Ptr(branch.Name),
+			To:   gitlab.Ptr(compareTo),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to compare branches: %v", err)), nil
+		}
+		result["ahead"] = len(ahead.Commits)
+		result["behind"] = len(behind.Commits)
+	} else {
+		result["ahead"] = 0
+		result["behind"] = 0
+	}
+
+	return jsonResult(result)
+}
+
+func handleDeleteBranch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	_, err := gitlabClient.Branches.DeleteBranch(projectID, branch)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete branch: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action": "deleted",
+		"branch": branch,
+	}
+	return jsonResult(result)
+}
+
+func handleDeleteMergedBranches(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	dryRun := getBool(args, "dry_run", true)
+	includeWithoutMR := getBool(args, "include_without_merge_request", false)
+
+	opts := &gitlab.ListBranchesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+	}
+	if search := getString(args, "search", ""); search != "" {
+		opts.Search = gitlab.Ptr(search)
+	}
+
+	var merged []*gitlab.Branch
+	for {
+		branches, resp, err := gitlabClient.Branches.ListBranches(projectID, opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list branches: %v", err)), nil
+		}
+		for _, b := range branches {
+			if b.Merged && !b.Protected && !b.Default {
+				merged = append(merged, b)
+			}
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// 自分のコミットを持たないブランチ (デフォルトブランチの過去のコミットから作っただけのもの) も
+	// merged と判定されるため、マージ済み MR の作成元になったブランチだけを対象にする
+	candidates := merged
+	var withoutMR []map[string]interface{}
+	if !includeWithoutMR {
+		hasMR, err := branchesWithMergedMergeRequest(ctx, projectID, merged)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list merge requests: %v", err)), nil
+		}
+		candidates = nil
+		for i, b := range merged {
+			if hasMR[i] {
+				candidates = append(candidates, b)
+				continue
+			}
+			item := map[string]interface{}{
+				"name": b.Name,
+			}
+			if b.Commit != nil {
+				item["commit"] = b.Commit.ShortID
+			}
+			withoutMR = append(withoutMR, item)
+		}
+	}
+
+	// 一括削除 API は対象を返さないため 1 ブランチずつ削除して結果を報告する
+	var deleted, failed []map[string]interface{}
+	for _, b := range candidates {
+		item := map[string]interface{}{
+			"name": b.Name,
+		}
+		if b.Commit != nil {
+			item["commit"] = b.Commit.ShortID
+			item["committed_date"] = b.Commit.CommittedDate
+		}
+
+		if !dryRun {
+			if _, err := gitlabClient.Branches.DeleteBranch(projectID, b.Name); err != nil {
+				item["error"] = err.Error()
+				failed = append(failed, item)
+				continue
+			}
+		}
+		deleted = append(deleted, item)
+	}
+
+	result := map[string]interface{}{
+		"dry_run":                       dryRun,
+		"count":                         len(deleted),
+		"skipped_without_merge_request": withoutMR,
+	}
+	if dryRun {
+		result["would_delete"] = deleted
+	} else {
+		result["deleted"] = deleted
+		result["failed"] = failed
+	}
+	return jsonResult(result)
+}
+
+// branchesWithMergedMergeRequest は各ブランチがマージ済み MR の作成元になったことがあるかを並行して調べる
+func branchesWithMergedMergeRequest(ctx context.Context, projectID string, branches []*gitlab.Branch) ([]bool, error) {
+	hasMR := make([]bool, len(branches))
+	errs := make([]error, len(branches))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < defaultFetchConcurrency && w < len(branches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				mrs, _, err := gitlabClient.MergeRequests.ListProjectMergeRequests(projectID, &gitlab.ListProjectMergeRequestsOptions{
+					ListOptions:  gitlab.ListOptions{PerPage: 1},
+					SourceBranch: gitlab.Ptr(branches[i].Name),
+					State:        gitlab.Ptr("merged"),
+				}, gitlab.WithContext(ctx))
+				hasMR[i], errs[i] = len(mrs) > 0, err
+			}
+		}()
+	}
+	for i := range branches {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hasMR, nil
+}
+
+func handleProtectBranch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	opts := &gitlab.ProtectRepositoryBranchesOptions{
+		Name: gitlab.Ptr(branch),
+	}
+
+	for key, target := range map[string]**gitlab.AccessLevelValue{
+		"push_access_level":      &opts.PushAccessLevel,
+		"merge_access_level":     &opts.MergeAccessLevel,
+		"unprotect_access_level": &opts.UnprotectAccessLevel,
+	} {
+		name := getString(args, key, "")
+		if name == "" {
+			continue
+		}
+		level, ok := accessLevels[name]
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid %s: %s (must be one of no_access, developer, maintainer, admin)", key, name)), nil
+		}
+		*target = gitlab.Ptr(level)
+	}
+
+	if allowForcePush, ok := args["allow_force_push"].(bool); ok {
+		opts.AllowForcePush = gitlab.Ptr(allowForcePush)
+	}
+	if codeOwner, ok := args["code_owner_approval_required"].(bool); ok {
+		opts.CodeOwnerApprovalRequired = gitlab.Ptr(codeOwner)
+	}
+
+	protected, _, err := gitlabClient.ProtectedBranches.ProtectRepositoryBranches(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to protect branch: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"name":                         protected.Name,
+		"push_access_levels":           accessLevelDescriptions(protected.PushAccessLevels),
+		"merge_access_levels":          accessLevelDescriptions(protected.MergeAccessLevels),
+		"unprotect_access_levels":      accessLevelDescriptions(protected.UnprotectAccessLevels),
+		"allow_force_push":             protected.AllowForcePush,
+		"code_owner_approval_required": protected.CodeOwnerApprovalRequired,
+	}
+	return jsonResult(result)
+}
+
+func handleUnprotectBranch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	_, err := gitlabClient.ProtectedBranches.UnprotectRepositoryBranches(projectID, branch)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to unprotect branch: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action": "unprotected",
+		"branch": branch,
+	}
+	return jsonResult(result)
+}
+
+// accessLevelDescriptions はアクセスレベルの説明を "Maintainers" のような文字列で返す
+func accessLevelDescriptions(levels []*gitlab.BranchAccessDescription) string {
+	descriptions := make([]string, len(levels))
+	for i, l := range levels {
+		descriptions[i] = l.AccessLevelDescription
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,752 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// 差分 1 ファイルあたりの返却上限 (デフォルト)
+const defaultMaxDiffBytes = 20 * 1024
+
+func registerCommitTools(s *server.MCPServer) {
+	// コミット一覧取得
+	s.AddTool(
+		mcp.NewTool("list_commits",
+			mcp.WithDescription("List commits in a GitLab project, optionally filtered by ref, path, date range and author"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch, tag, or commit SHA (default: default branch)"),
+			),
+			mcp.WithString("path",
+				mcp.Description("Only commits touching this file or directory"),
+			),
+			mcp.WithString("since",
+				mcp.Description("Only commits after this date (ISO 8601, e.g. 2024-01-31 or 2024-01-31T12:00:00Z)"),
+			),
+			mcp.WithString("until",
+				mcp.Description("Only commits before this date (ISO 8601)"),
+			),
+			mcp.WithString("author",
+				mcp.Description("Filter by author name or email"),
+			),
+			mcp.WithBoolean("with_stats",
+				mcp.Description("Include additions/deletions per commit (default: false)"),
+			),
+			mcp.WithBoolean("first_parent",
+				mcp.Description("Follow only the first parent of merge commits (default: false)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of commits per page (default: 20)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleListCommits,
+	)
+
+	// コミット詳細取得
+	s.AddTool(
+		mcp.NewTool("get_commit",
+			mcp.WithDescription("Get details of a commit including message, stats and parent SHAs"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("Commit SHA, branch or tag name"),
+			),
+		),
+		handleGetCommit,
+	)
+
+	// コミット差分取得
+	s.AddTool(
+		mcp.NewTool("get_commit_diff",
+			mcp.WithDescription("Get the diff of a commit, with per-file size limits"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("Commit SHA, branch or tag name"),
+			),
+			mcp.WithNumber("max_bytes",
+				mcp.Description("Maximum bytes of diff per file (default: 20480)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of files per page (default: 20)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleGetCommitDiff,
+	)
+
+	// コミットを含むブランチ・タグ取得
+	s.AddTool(
+		mcp.NewTool("get_commit_refs",
+			mcp.WithDescription("List branches and tags that contain a commit"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("Commit SHA"),
+			),
+			mcp.WithString("type",
+				mcp.Description("Filter by ref type: branch, tag, all (default: all)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of refs per page (default: 100)"),
+			),
+		),
+		handleGetCommitRefs,
+	)
+
+	// ref 間の比較
+	s.AddTool(
+		mcp.NewTool("compare_refs",
+			mcp.WithDescription("Compare two branches, tags or commits, returning commits, changed files with additions/deletions and truncated diffs"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("from",
+				mcp.Required(),
+				mcp.Description("Base branch, tag or commit SHA"),
+			),
+			mcp.WithString("to",
+				mcp.Required(),
+				mcp.Description("Head branch, tag or commit SHA"),
+			),
+			mcp.WithBoolean("straight",
+				mcp.Description("Compare from..to directly instead of from the merge base (from...to) (default: false)"),
+			),
+			mcp.WithBoolean("summary_only",
+				mcp.Description("Return only commits and per-file stats without diff bodies, for huge comparisons (default: false)"),
+			),
+			mcp.WithNumber("max_bytes",
+				mcp.Description("Maximum bytes of diff per file (default: 20480)"),
+			),
+			mcp.WithNumber("max_total_bytes",
+				mcp.Description("Maximum bytes of diff over all files; files beyond the budget are returned without diff (default: 524288)"),
+			),
+			mcp.WithNumber("max_commits",
+				mcp.Description("Maximum number of commits to return (default: 100)"),
+			),
+		),
+		handleCompareRefs,
+	)
+
+	// コミットのチェリーピック
+	s.AddTool(
+		mcp.NewTool("cherry_pick_commit",
+			mcp.WithDescription("Cherry-pick a commit onto a branch, or open a merge request with the cherry-pick. Returns the new commit or a structured conflict error"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("SHA of the commit to cherry-pick"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Target branch"),
+			),
+			mcp.WithString("message",
+				mcp.Description("Custom commit message (default: original commit message)"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Only check whether the change applies cleanly, without committing (default: false)"),
+			),
+			mcp.WithBoolean("create_merge_request",
+				mcp.Description("Commit to a new branch created from the target branch and open a merge request into it instead of committing directly (default: false)"),
+			),
+			mcp.WithString("mr_source_branch",
+				mcp.Description("Name of the branch created for the merge request (default: cherry-pick-<short sha>-<branch>)"),
+			),
+		),
+		handleCherryPickCommit,
+	)
+
+	// コミットの取り消し
+	s.AddTool(
+		mcp.NewTool("revert_commit",
+			mcp.WithDescription("Revert a commit on a branch, or open a merge request with the revert. Returns the new commit or a structured conflict error"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("sha",
+				mcp.Required(),
+				mcp.Description("SHA of the commit to revert"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Target branch"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Only check whether the change applies cleanly, without committing (default: false)"),
+			),
+			mcp.WithBoolean("create_merge_request",
+				mcp.Description("Commit to a new branch created from the target branch and open a merge request into it instead of committing directly (default: false)"),
+			),
+			mcp.WithString("mr_source_branch",
+				mcp.Description("Name of the branch created for the merge request (default: revert-<short sha>-<branch>)"),
+			),
+		),
+		handleRevertCommit,
+	)
+}
+
+func handleListCommits(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	perPage := getInt(args, "per_page", 20)
+	page := getInt(args, "page", 1)
+	withStats := getBool(args, "with_stats", false)
+
+	opts := &gitlab.ListCommitsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		},
+	}
+	if ref := getString(args, "ref", ""); ref != "" {
+		opts.RefName = gitlab.Ptr(ref)
+	}
+	if commitPath := getString(args, "path", ""); commitPath != "" {
+		opts.Path = gitlab.Ptr(commitPath)
+	}
+	if author := getString(args, "author", ""); author != "" {
+		opts.Author = gitlab.Ptr(author)
+	}
+	if since := getString(args, "since", ""); since != "" {
+		t, err := parseTimeArg(since)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid since: %v", err)), nil
+		}
+		opts.Since = gitlab.Ptr(t)
+	}
+	if until := getString(args, "until", ""); until != "" {
+		t, err := parseTimeArg(until)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid until: %v", err)), nil
+		}
+		opts.Until = gitlab.Ptr(t)
+	}
+	if withStats {
+		opts.WithStats = gitlab.Ptr(true)
+	}
+	if firstParent, ok := args["first_parent"].(bool); ok {
+		opts.FirstParent = gitlab.Ptr(firstParent)
+	}
+
+	commits, resp, err := gitlabClient.Commits.ListCommits(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list commits: %v", err)), nil
+	}
+
+	items := make([]map[string]interface{}, len(commits))
+	for i, c := range commits {
+		items[i] = commitSummary(c)
+	}
+
+	nextPage := 0
+	if resp != nil {
+		nextPage = resp.NextPage
+	}
+
+	result := map[string]interface{}{
+		"commits":   items,
+		"next_page": nextPage,
+	}
+	return jsonResult(result)
+}
+
+func handleGetCommit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	sha, ok := args["sha"].(string)
+	if !ok || sha == "" {
+		return mcp.NewToolResultError("sha is required"), nil
+	}
+
+	commit, _, err := gitlabClient.Commits.GetCommit(projectID, sha, &gitlab.GetCommitOptions{
+		Stats: gitlab.Ptr(true),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get commit: %v", err)), nil
+	}
+
+	result := commitSummary(commit)
+	result["message"] = commit.Message
+	result["author_email"] = commit.AuthorEmail
+	result["authored_date"] = commit.AuthoredDate
+	result["committer_name"] = commit.CommitterName
+	result["committer_email"] = commit.CommitterEmail
+	result["committed_date"] = commit.CommittedDate
+	if commit.LastPipeline != nil {
+		result["last_pipeline"] = map[string]interface{}{
+			"id":     commit.LastPipeline.ID,
+			"status": commit.LastPipeline.Status,
+			"ref":    commit.LastPipeline.Ref,
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleGetCommitDiff(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	sha, ok := args["sha"].(string)
+	if !ok || sha == "" {
+		return mcp.NewToolResultError("sha is required"), nil
+	}
+
+	maxBytes := getInt(args, "max_bytes", defaultMaxDiffBytes)
+	perPage := getInt(args, "per_page", 20)
+	page := getInt(args, "page", 1)
+
+	diffs, resp, err := gitlabClient.Commits.GetCommitDiff(projectID, sha, &gitlab.GetCommitDiffOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		},
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get commit diff: %v", err)), nil
+	}
+
+	files := make([]map[string]interface{}, len(diffs))
+	for i, d := range diffs {
+		files[i] = diffSummary(d, maxBytes, true)
+	}
+
+	nextPage := 0
+	if resp != nil {
+		nextPage = resp.NextPage
+	}
+
+	result := map[string]interface{}{
+		"sha":       sha,
+		"files":     files,
+		"next_page": nextPage,
+	}
+	return jsonResult(result)
+}
+
+func handleGetCommitRefs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	sha, ok := args["sha"].(string)
+	if !ok || sha == "" {
+		return mcp.NewToolResultError("sha is required"), nil
+	}
+
+	perPage := getInt(args, "per_page", 100)
+
+	refs, _, err := gitlabClient.Commits.GetCommitRefs(projectID, sha, &gitlab.GetCommitRefsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+		},
+		Type: gitlab.Ptr(getString(args, "type", "all")),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get commit refs: %v", err)), nil
+	}
+
+	branches := []string{}
+	tags := []string{}
+	for _, r := range refs {
+		switch r.Type {
+		case "branch":
+			branches = append(branches, r.Name)
+		case "tag":
+			tags = append(tags, r.Name)
+		}
+	}
+
+	result := map[string]interface{}{
+		"sha":      sha,
+		"branches": branches,
+		"tags":     tags,
+	}
+	return jsonResult(result)
+}
+
+func handleCompareRefs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	from, ok := args["from"].(string)
+	if !ok || from == "" {
+		return mcp.NewToolResultError("from is required"), nil
+	}
+
+	to, ok := args["to"].(string)
+	if !ok || to == "" {
+		return mcp.NewToolResultError("to is required"), nil
+	}
+
+	summaryOnly := getBool(args, "summary_only", false)
+	maxBytes := getInt(args, "max_bytes", defaultMaxDiffBytes)
+	maxTotalBytes := getInt(args, "max_total_bytes", defaultMaxTotalBytes)
+	maxCommits := getInt(args, "max_commits", 100)
+	if maxBytes < 1 || maxTotalBytes < 1 || maxCommits < 1 {
+		return mcp.NewToolResultError("max_bytes, max_total_bytes and max_commits must be at least 1"), nil
+	}
+
+	compare, _, err := gitlabClient.Repositories.Compare(projectID, &gitlab.CompareOptions{
+		From:     gitlab.Ptr(from),
+		To:       gitlab.Ptr(to),
+		Straight: gitlab.Ptr(getBool(args, "straight", false)),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare refs: %v", err)), nil
+	}
+
+	commits := make([]map[string]interface{}, 0, min(len(compare.Commits), maxCommits))
+	for _, c := range compare.Commits {
+		if len(commits) >= maxCommits {
+			break
+		}
+		commits = append(commits, map[string]interface{}{
+			"id":          c.ShortID,
+			"title":       c.Title,
+			"author_name": c.AuthorName,
+			"created_at":  c.CreatedAt,
+		})
+	}
+
+	// 入力順に合計サイズの上限を適用
+	totalAdditions, totalDeletions, used := 0, 0, 0
+	files := make([]map[string]interface{}, len(compare.Diffs))
+	for i, d := range compare.Diffs {
+		files[i] = diffSummary(d, maxBytes, !summaryOnly)
+		totalAdditions += files[i]["additions"].(int)
+		totalDeletions += files[i]["deletions"].(int)
+
+		diff, ok := files[i]["diff"].(string)
+		if !ok {
+			continue
+		}
+		if used+len(diff) > maxTotalBytes {
+			delete(files[i], "diff")
+			files[i]["skipped"] = "total size budget exceeded"
+			continue
+		}
+		used += len(diff)
+	}
+
+	result := map[string]interface{}{
+		"from":             from,
+		"to":               to,
+		"commits":          commits,
+		"commits_count":    len(compare.Commits),
+		"commits_omitted":  len(compare.Commits) - len(commits),
+		"files":            files,
+		"files_changed":    len(files),
+		"additions":        totalAdditions,
+		"deletions":        totalDeletions,
+		"compare_timeout":  compare.CompareTimeout,
+		"compare_same_ref": compare.CompareSameRef,
+		"web_url":          compare.WebURL,
+	}
+	return jsonResult(result)
+}
+
+func handleCherryPickCommit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return applyCommitToBranch(req.Params.Arguments, false)
+}
+
+func handleRevertCommit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return applyCommitToBranch(req.Params.Arguments, true)
+}
+
+// revertCommitOptions は go-gitlab の RevertCommitOptions に dry_run を加えたもの
+type revertCommitOptions struct {
+	Branch *string `url:"branch,omitempty" json:"branch,omitempty"`
+	DryRun *bool   `url:"dry_run,omitempty" json:"dry_run,omitempty"`
+}
+
+// applyCommitToBranch はコミットをブランチにチェリーピックまたはリバートする。
+// create_merge_request が指定された場合は新しいブランチに適用して MR を作成する。
+func applyCommitToBranch(args map[string]interface{}, revert bool) (*mcp.CallToolResult, error) {
+	operation := "cherry-pick"
+	if revert {
+		operation = "revert"
+	}
+
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	sha, ok := args["sha"].(string)
+	if !ok || sha == "" {
+		return mcp.NewToolResultError("sha is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	dryRun := getBool(args, "dry_run", false)
+	createMR := getBool(args, "create_merge_request", false)
+
+	source, _, err := gitlabClient.Commits.GetCommit(projectID, sha, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get commit: %v", err)), nil
+	}
+
+	// MR を作成する場合は対象ブランチから作業ブランチを切る
+	targetBranch := branch
+	if createMR && !dryRun {
+		targetBranch = getString(args, "mr_source_branch", fmt.Sprintf("%s-%s-%s", operation, source.ShortID, branch))
+		_, _, err := gitlabClient.Branches.CreateBranch(projectID, &gitlab.CreateBranchOptions{
+			Branch: gitlab.Ptr(targetBranch),
+			Ref:    gitlab.Ptr(branch),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create branch: %v", err)), nil
+		}
+	}
+
+	var commit *gitlab.Commit
+	if revert {
+		commit, err = revertCommit(projectID, source.ID, targetBranch, dryRun)
+	} else {
+		opts := &gitlab.CherryPickCommitOptions{
+			Branch: gitlab.Ptr(targetBranch),
+		}
+		if dryRun {
+			opts.DryRun = gitlab.Ptr(true)
+		}
+		if message := getString(args, "message", ""); message != "" {
+			opts.Message = gitlab.Ptr(message)
+		}
+		commit, _, err = gitlabClient.Commits.CherryPickCommit(projectID, source.ID, opts)
+	}
+	if err != nil {
+		// 失敗した場合は作成した作業ブランチを残さない
+		var cleanupErr error
+		if targetBranch != branch {
+			if _, err := gitlabClient.Branches.DeleteBranch(projectID, targetBranch); err != nil {
+				cleanupErr = fmt.Errorf("failed to delete branch %s: %v", targetBranch, err)
+			}
+		}
+		return commitConflictResult(operation, source.ID, branch, err, cleanupErr), nil
+	}
+
+	if dryRun {
+		result := map[string]interface{}{
+			"dry_run": true,
+			"sha":     source.ID,
+			"branch":  branch,
+			"message": fmt.Sprintf("%s applies cleanly", operation),
+		}
+		return jsonResult(result)
+	}
+
+	result := map[string]interface{}{
+		"sha":     source.ID,
+		"branch":  targetBranch,
+		"commit":  commitSummary(commit),
+		"message": commit.Message,
+	}
+
+	if createMR {
+		title := fmt.Sprintf("Cherry-pick '%s' into '%s'", source.Title, branch)
+		if revert {
+			title = fmt.Sprintf("Revert \"%s\"", source.Title)
+		}
+		mr, _, err := gitlabClient.MergeRequests.CreateMergeRequest(projectID, &gitlab.CreateMergeRequestOptions{
+			SourceBranch:       gitlab.Ptr(targetBranch),
+			TargetBranch:       gitlab.Ptr(branch),
+			Title:              gitlab.Ptr(title),
+			Description:        gitlab.Ptr(fmt.Sprintf("%s of %s", operation, source.ID)),
+			RemoveSourceBranch: gitlab.Ptr(true),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Committed to %s but failed to create merge request: %v (the branch was kept; create the merge request manually or delete the branch)", targetBranch, err)), nil
+		}
+		result["merge_request"] = map[string]interface{}{
+			"iid":     mr.IID,
+			"title":   mr.Title,
+			"web_url": mr.WebURL,
+		}
+	}
+
+	return jsonResult(result)
+}
+
+// revertCommit は dry_run に対応するため直接リクエストを組み立ててリバート API を呼び出す
+func revertCommit(projectID, sha, branch string, dryRun bool) (*gitlab.Commit, error) {
+	opts := &revertCommitOptions{Branch: gitlab.Ptr(branch)}
+	if dryRun {
+		opts.DryRun = gitlab.Ptr(true)
+	}
+
+	u := fmt.Sprintf("projects/%s/repository/commits/%s/revert", gitlab.PathEscape(projectID), url.PathEscape(sha))
+	httpReq, err := gitlabClient.NewRequest(http.MethodPost, u, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := new(gitlab.Commit)
+	if _, err := gitlabClient.Do(httpReq, commit); err != nil {
+		return nil, err
+	}
+	return commit, nil
+}
+
+// commitConflictResult はチェリーピック・リバートの失敗を構造化エラーとして返す。
+// GitLab は競合時に error_code "conflict"、変更が空の場合に "empty" を返す。
+// cleanupErr は作業ブランチの削除に失敗した場合のエラーで、結果に含めて呼び出し元に知らせる。
+func commitConflictResult(operation, sha, branch string, err, cleanupErr error) *mcp.CallToolResult {
+	plainError := func() *mcp.CallToolResult {
+		message := fmt.Sprintf("Failed to %s commit: %v", operation, err)
+		if cleanupErr != nil {
+			message += fmt.Sprintf("; cleanup also failed: %v", cleanupErr)
+		}
+		return mcp.NewToolResultError(message)
+	}
+
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || len(errResp.Body) == 0 {
+		return plainError()
+	}
+
+	var body struct {
+		Message   string `json:"message"`
+		ErrorCode string `json:"error_code"`
+	}
+	if json.Unmarshal(errResp.Body, &body) != nil || body.ErrorCode == "" {
+		return plainError()
+	}
+
+	result := map[string]interface{}{
+		"error":      body.ErrorCode,
+		"operation":  operation,
+		"message":    body.Message,
+		"sha":        sha,
+		"branch":     branch,
+		"suggestion": "resolve the conflict locally or apply the change manually with apply_patch",
+	}
+	if cleanupErr != nil {
+		result["cleanup_error"] = cleanupErr.Error()
+	}
+	jsonBytes, _ := json.MarshalIndent(result, "", "  ")
+	return mcp.NewToolResultError(string(jsonBytes))
+}
+
+// commitSummary はコミット一覧や詳細で共通の項目を返す
+func commitSummary(c *gitlab.Commit) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":          c.ID,
+		"short_id":    c.ShortID,
+		"title":       c.Title,
+		"author_name": c.AuthorName,
+		"created_at":  c.CreatedAt,
+		"parent_ids":  c.ParentIDs,
+		"web_url":     c.WebURL,
+	}
+	if c.Stats != nil {
+		result["stats"] = map[string]interface{}{
+			"additions": c.Stats.Additions,
+			"deletions": c.Stats.Deletions,
+			"total":     c.Stats.Total,
+		}
+	}
+	return result
+}
+
+// diffSummary は 1 ファイル分の差分を追加・削除行数付きで返す。
+// includeDiff が true の場合は maxBytes で切り詰めた差分本文も含める。
+func diffSummary(d *gitlab.Diff, maxBytes int, includeDiff bool) map[string]interface{} {
+	additions, deletions := diffLineCounts(d.Diff)
+
+	result := map[string]interface{}{
+		"old_path":     d.OldPath,
+		"new_path":     d.NewPath,
+		"new_file":     d.NewFile,
+		"renamed_file": d.RenamedFile,
+		"deleted_file": d.DeletedFile,
+		"additions":    additions,
+		"deletions":    deletions,
+	}
+	if d.AMode != d.BMode && d.AMode != "0" && d.BMode != "0" {
+		result["old_mode"] = d.AMode
+		result["new_mode"] = d.BMode
+	}
+
+	if includeDiff {
+		result["diff"] = truncateString(d.Diff, maxBytes)
+		result["truncated"] = maxBytes > 0 && len(d.Diff) > maxBytes
+	}
+	return result
+}
+
+// diffLineCounts は unified diff 本文の追加行数と削除行数を数える。
+// API の差分はハンクから始まりファイルヘッダーを含まない。
+func diffLineCounts(diff string) (int, int) {
+	additions, deletions := 0, 0
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			additions++
+		case strings.HasPrefix(line, "-"):
+			deletions++
+		}
+	}
+	return additions, deletions
+}
+
+// parseTimeArg は RFC 3339 の日時または YYYY-MM-DD 形式の日付を解釈する
+func parseTimeArg(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
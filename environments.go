@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+func registerEnvironmentTools(s *server.MCPServer) {
+	// 環境一覧取得
+	s.AddTool(
+		mcp.NewTool("list_environments",
+			mcp.WithDescription("List environments in a GitLab project with their last deployment"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("states",
+				mcp.Description("Filter by state: available, stopping, stopped"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Search environments by name"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of environments per page (default: 20, max: 100)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+			mcp.WithBoolean("include_last_deployment",
+				mcp.Description("Fetch each environment's last deployment, one request per environment (default: true)"),
+			),
+		),
+		handleListEnvironments,
+	)
+
+	// デプロイ一覧取得
+	s.AddTool(
+		mcp.NewTool("list_deployments",
+			mcp.WithDescription("List deployments in a GitLab project, optionally filtered by environment and status"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("environment",
+				mcp.Description("Environment name"),
+			),
+			mcp.WithString("status",
+				mcp.Description("Filter by status: created, running, success, failed, canceled, blocked"),
+			),
+			mcp.WithString("order_by",
+				mcp.Description("Order by: id, iid, created_at, updated_at, finished_at, ref (default: id)"),
+			),
+			mcp.WithString("sort",
+				mcp.Description("Sort order: asc, desc (default: desc)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of deployments per page (default: 20)"),
+			),
+		),
+		handleListDeployments,
+	)
+
+	// デプロイに含まれるコミット範囲の取得
+	s.AddTool(
+		mcp.NewTool("get_deployment_commits",
+			mcp.WithDescription("Get the commit range of a deployment (since the previous successful deployment to the same environment), or the commits not yet deployed when compare_to is given"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("deployment_id",
+				mcp.Required(),
+				mcp.Description("Deployment ID"),
+			),
+			mcp.WithString("compare_to",
+				mcp.Description("Branch, tag or SHA to compare the deployed SHA against (e.g., 'main' to see what is not yet deployed)"),
+			),
+		),
+		handleGetDeploymentCommits,
+	)
+
+	// 環境の停止
+	s.AddTool(
+		mcp.NewTool("stop_environment",
+			mcp.WithDescription("Stop an environment"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("environment_id",
+				mcp.Required(),
+				mcp.Description("Environment ID"),
+			),
+			mcp.WithBoolean("force",
+				mcp.Description("Force stop without running on_stop actions (default: false)"),
+			),
+		),
+		handleStopEnvironment,
+	)
+}
+
+func handleListEnvironments(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	perPage := min(getInt(args, "per_page", 20), 100)
+	includeLastDeployment := getBool(args, "include_last_deployment", true)
+
+	opts := &gitlab.ListEnvironmentsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    getInt(args, "page", 1),
+		},
+	}
+	if states := getString(args, "states", ""); states != "" {
+		opts.States = gitlab.Ptr(states)
+	}
+	if search := getString(args, "search", ""); search != "" {
+		opts.Search = gitlab.Ptr(search)
+	}
+
+	envs, _, err := gitlabClient.Environments.ListEnvironments(projectID, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list environments: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(envs))
+	for i, e := range envs {
+		result[i] = map[string]interface{}{
+			"id":           e.ID,
+			"name":         e.Name,
+			"state":        e.State,
+			"tier":         e.Tier,
+			"external_url": e.ExternalURL,
+		}
+	}
+	if !includeLastDeployment {
+		return jsonResult(result)
+	}
+
+	// 一覧 API は最終デプロイを含まないため、ワーカープールで個別に取得する
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < defaultFetchConcurrency && w < len(envs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				env, _, err := gitlabClient.Environments.GetEnvironment(projectID, envs[i].ID, gitlab.WithContext(ctx))
+				if err != nil {
+					result[i]["last_deployment_error"] = err.Error()
+					continue
+				}
+				if env.LastDeployment != nil {
+					result[i]["last_deployment"] = deploymentSummary(env.LastDeployment)
+				}
+			}
+		}()
+	}
+	for i := range envs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return jsonResult(result)
+}
+
+func handleListDeployments(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	perPage := getInt(args, "per_page", 20)
+
+	opts := &gitlab.ListProjectDeploymentsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+		},
+		OrderBy: gitlab.Ptr(getString(args, "order_by", "id")),
+		Sort:    gitlab.Ptr(getString(args, "sort", "desc")),
+	}
+	if environment := getString(args, "environment", ""); environment != "" {
+		opts.Environment = gitlab.Ptr(environment)
+	}
+	if status := getString(args, "status", ""); status != "" {
+		opts.Status = gitlab.Ptr(status)
+	}
+
+	deployments, _, err := gitlabClient.Deployments.ListProjectDeployments(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list deployments: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(deployments))
+	for i, d := range deployments {
+		result[i] = deploymentSummary(d)
+	}
+
+	return jsonResult(result)
+}
+
+func handleGetDeploymentCommits(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	deploymentID := getInt(args, "deployment_id", 0)
+	if deploymentID <= 0 {
+		return mcp.NewToolResultError("deployment_id is required"), nil
+	}
+
+	deployment, _, err := gitlabClient.Deployments.GetProjectDeployment(projectID, deploymentID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get deployment: %v", err)), nil
+	}
+
+	from, to := "", deployment.SHA
+	var previous *gitlab.Deployment
+
+	if compareTo := getString(args, "compare_to", ""); compareTo != "" {
+		// デプロイ済み SHA から指定 ref までの未デプロイ分
+		from, to = deployment.SHA, compareTo
+	} else if deployment.Environment != nil {
+		// 同じ環境への直前の成功デプロイを ID の降順にページをたどって探す
+		opts := &gitlab.ListProjectDeploymentsOptions{
+			ListOptions: gitlab.ListOptions{
+				PerPage: 100,
+				Page:    1,
+			},
+			Environment: gitlab.Ptr(deployment.Environment.Name),
+			Status:      gitlab.Ptr("success"),
+			OrderBy:     gitlab.Ptr("id"),
+			Sort:        gitlab.Ptr("desc"),
+		}
+	search:
+		for {
+			deployments, resp, err := gitlabClient.Deployments.ListProjectDeployments(projectID, opts, gitlab.WithContext(ctx))
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to list deployments: %v", err)), nil
+			}
+			for _, d := range deployments {
+				if d.ID < deployment.ID {
+					previous = d
+					break search
+				}
+			}
+
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		if previous != nil {
+			from = previous.SHA
+		}
+	}
+
+	result := map[string]interface{}{
+		"deployment": deploymentSummary(deployment),
+		"from":       from,
+		"to":         to,
+	}
+	if previous != nil {
+		result["previous_deployment"] = deploymentSummary(previous)
+	}
+
+	if from == "" {
+		// 最初のデプロイの場合は比較対象がない
+		result["commits"] = []map[string]interface{}{}
+		result["note"] = "No previous successful deployment to this environment was found"
+		return jsonResult(result)
+	}
+
+	compare, _, err := gitlabClient.Repositories.Compare(projectID, &gitlab.CompareOptions{
+		From: gitlab.Ptr(from),
+		To:   gitlab.Ptr(to),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare commits: %v", err)), nil
+	}
+
+	commits := make([]map[string]interface{}, len(compare.Commits))
+	for i, c := range compare.Commits {
+		commits[i] = map[string]interface{}{
+			"id":          c.ShortID,
+			"title":       c.Title,
+			"author_name": c.AuthorName,
+			"created_at":  c.CreatedAt,
+		}
+	}
+	result["commits"] = commits
+	result["commits_count"] = len(commits)
+	result["compare_url"] = compare.WebURL
+
+	return jsonResult(result)
+}
+
+func handleStopEnvironment(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	environmentID := getInt(args, "environment_id", 0)
+	if environmentID <= 0 {
+		return mcp.NewToolResultError("environment_id is required"), nil
+	}
+
+	opts := &gitlab.StopEnvironmentOptions{}
+	if force, ok := args["force"].(bool); ok {
+		opts.Force = gitlab.Ptr(force)
+	}
+
+	env, _, err := gitlabClient.Environments.StopEnvironment(projectID, environmentID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to stop environment: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"id":    env.ID,
+		"name":  env.Name,
+		"state": env.State,
+	}
+	return jsonResult(result)
+}
+
+func deploymentSummary(d *gitlab.Deployment) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":         d.ID,
+		"iid":        d.IID,
+		"ref":        d.Ref,
+		"sha":        d.SHA,
+		"status":     d.Status,
+		"created_at": d.CreatedAt,
+		"updated_at": d.UpdatedAt,
+	}
+	if d.Environment != nil {
+		result["environment"] = d.Environment.Name
+	}
+	if d.User != nil {
+		result["user"] = d.User.Username
+	}
+	if d.Deployable.ID != 0 {
+		result["job"] = d.Deployable.Name
+		result["job_id"] = d.Deployable.ID
+	}
+	return result
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	// ファイル内容の返却上限 (デフォルト)
+	defaultMaxFileBytes = 100 * 1024
+	// get_files で返す内容の合計上限 (デフォルト)
+	defaultMaxTotalBytes = 512 * 1024
+	// get_files の同時取得数 (デフォルト / 上限)
+	defaultFetchConcurrency = 8
+	maxFetchConcurrency     = 16
+)
+
+func registerFileTools(s *server.MCPServer) {
+	// 複数ファイルの一括取得
+	s.AddTool(
+		mcp.NewTool("get_files",
+			mcp.WithDescription("Get contents of many files at one ref in a single call. Accepts paths and glob patterns (e.g., 'src/**/*.go')"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithArray("paths",
+				mcp.Required(),
+				mcp.Description("Array of file paths or glob patterns ('*', '?', '**')"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch, tag, or commit SHA (default: default branch)"),
+			),
+			mcp.WithNumber("max_bytes",
+				mcp.Description("Maximum bytes of content per file (default: 102400)"),
+			),
+			mcp.WithNumber("max_total_bytes",
+				mcp.Description("Maximum bytes of content over all files; files beyond the budget are returned without content (default: 524288)"),
+			),
+			mcp.WithNumber("max_files",
+				mcp.Description("Maximum number of files to fetch (default: 50)"),
+			),
+			mcp.WithNumber("concurrency",
+				mcp.Description("Number of files fetched concurrently (default: 8, max: 16)"),
+			),
+		),
+		handleGetFiles,
+	)
+
+	// ファイルの blame 取得
+	s.AddTool(
+		mcp.NewTool("get_file_blame",
+			mcp.WithDescription("Get blame information for a file: which commit (SHA, author, date, message) last changed each span of lines"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("file_path",
+				mcp.Required(),
+				mcp.Description("Path to the file in the repository"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch, tag, or commit SHA (default: default branch)"),
+			),
+			mcp.WithNumber("start_line",
+				mcp.Description("First line to blame, 1-based (default: 1)"),
+			),
+			mcp.WithNumber("end_line",
+				mcp.Description("Last line to blame, inclusive (default: last line)"),
+			),
+			mcp.WithBoolean("include_lines",
+				mcp.Description("Include the text of each line in the spans (default: false)"),
+			),
+			mcp.WithString("format",
+				mcp.Description("Output format: json (commits and spans) or text (one line per span) (default: json)"),
+			),
+		),
+		handleGetFileBlame,
+	)
+}
+
+func handleGetFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	pathsArg, ok := args["paths"].([]interface{})
+	if !ok || len(pathsArg) == 0 {
+		return mcp.NewToolResultError("paths is required and must be a non-empty array"), nil
+	}
+
+	ref := getString(args, "ref", "")
+	maxBytes := getInt(args, "max_bytes", defaultMaxFileBytes)
+	maxTotalBytes := getInt(args, "max_total_bytes", defaultMaxTotalBytes)
+	maxFiles := getInt(args, "max_files", 50)
+	if maxBytes < 1 || maxTotalBytes < 1 || maxFiles < 1 {
+		return mcp.NewToolResultError("max_bytes, max_total_bytes and max_files must be at least 1"), nil
+	}
+	concurrency := getInt(args, "concurrency", defaultFetchConcurrency)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > maxFetchConcurrency {
+		concurrency = maxFetchConcurrency
+	}
+
+	var patterns []string
+	for _, p := range pathsArg {
+		pattern, ok := p.(string)
+		if !ok || pattern == "" {
+			return mcp.NewToolResultError("each path must be a non-empty string"), nil
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	paths, err := resolveFilePatterns(projectID, ref, patterns)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve paths: %v", err)), nil
+	}
+
+	omitted := 0
+	if len(paths) > maxFiles {
+		omitted = len(paths) - maxFiles
+		paths = paths[:maxFiles]
+	}
+
+	// ワーカープールで並行取得
+	results := make([]map[string]interface{}, len(paths))
+	indexes := make(chan int)
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		fetched int
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := fetchFileResult(ctx, projectID, paths[i], ref, maxBytes)
+				if content, ok := r["content"].(string); ok {
+					mu.Lock()
+					fetched += len(content)
+					mu.Unlock()
+				}
+				results[i] = r
+			}
+		}()
+	}
+
+	// 取得済みの合計が上限に達したら残りのファイルはダウンロードしない
+dispatch:
+	for i := range paths {
+		mu.Lock()
+		exhausted := fetched >= maxTotalBytes
+		mu.Unlock()
+		if exhausted {
+			break
+		}
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Fetching files was cancelled: %v", ctx.Err())), nil
+	}
+
+	// 入力順に合計サイズの上限を適用
+	used := 0
+	for i, r := range results {
+		if r == nil {
+			results[i] = map[string]interface{}{
+				"file_path": paths[i],
+				"skipped":   "total size budget exceeded",
+			}
+			continue
+		}
+		content, ok := r["content"].(string)
+		if !ok {
+			continue
+		}
+		if used+len(content) > maxTotalBytes {
+			delete(r, "content")
+			r["skipped"] = "total size budget exceeded"
+			continue
+		}
+		used += len(content)
+	}
+
+	result := map[string]interface{}{
+		"ref":           ref,
+		"files":         results,
+		"files_count":   len(results),
+		"files_omitted": omitted,
+		"total_bytes":   used,
+	}
+	return jsonResult(result)
+}
+
+func handleGetFileBlame(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return mcp.NewToolResultError("file_path is required"), nil
+	}
+
+	ref := getString(args, "ref", "")
+	startLine := getInt(args, "start_line", 1)
+	endLine := getInt(args, "end_line", 0)
+	includeLines := getBool(args, "include_lines", false)
+	format := getString(args, "format", "json")
+
+	if startLine < 1 {
+		startLine = 1
+	}
+	if endLine > 0 && endLine < startLine {
+		return mcp.NewToolResultError("end_line must be greater than or equal to start_line"), nil
+	}
+
+	opts := &gitlab.GetFileBlameOptions{}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	// 範囲指定は開始と終了の両方が必要なため、終了行がない場合は全体を取得して切り出す
+	if endLine > 0 {
+		opts.RangeStart = gitlab.Ptr(startLine)
+		opts.RangeEnd = gitlab.Ptr(endLine)
+	}
+
+	ranges, _, err := gitlabClient.RepositoryFiles.GetFileBlame(projectID, filePath, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get file blame: %v", err)), nil
+	}
+
+	line := 1
+	if endLine > 0 {
+		line = startLine
+	}
+
+	commits := make(map[string]map[string]interface{})
+	var spans []map[string]interface{}
+	var text strings.Builder
+	for _, r := range ranges {
+		first := line
+		line += len(r.Lines)
+		last := line - 1
+
+		lines := r.Lines
+		if first < startLine {
+			lines = lines[min(startLine-first, len(lines)):]
+			first = startLine
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		shortID := r.Commit.ID
+		if len(shortID) > 8 {
+			shortID = shortID[:8]
+		}
+		title, _, _ := strings.Cut(r.Commit.Message, "\n")
+		date := ""
+		if r.Commit.AuthoredDate != nil {
+			date = r.Commit.AuthoredDate.Format("2006-01-02")
+		}
+
+		if _, ok := commits[shortID]; !ok {
+			commits[shortID] = map[string]interface{}{
+				"id":          r.Commit.ID,
+				"author_name": r.Commit.AuthorName,
+				"date":        date,
+				"title":       title,
+			}
+		}
+
+		span := map[string]interface{}{
+			"start_line": first,
+			"end_line":   last,
+			"commit":     shortID,
+		}
+		if includeLines {
+			span["lines"] = lines
+		}
+		spans = append(spans, span)
+
+		fmt.Fprintf(&text, "L%d-%d %s %s %s %s\n", first, last, shortID, r.Commit.AuthorName, date, title)
+		if includeLines {
+			for i, l := range lines {
+				fmt.Fprintf(&text, "  %d: %s\n", first+i, l)
+			}
+		}
+	}
+
+	if format == "text" {
+		return mcp.NewToolResultText(text.String()), nil
+	}
+
+	result := map[string]interface{}{
+		"file_path": filePath,
+		"ref":       ref,
+		"commits":   commits,
+		"spans":     spans,
+	}
+	return jsonResult(result)
+}
+
+// fetchFileResult は 1 ファイルを取得し、失敗した場合はファイル単位のエラーを返す
+func fetchFileResult(ctx context.Context, projectID, filePath, ref string, maxBytes int) map[string]interface{} {
+	opts := &gitlab.GetFileOptions{}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+
+	file, _, err := gitlabClient.RepositoryFiles.GetFile(projectID, filePath, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return map[string]interface{}{
+			"file_path": filePath,
+			"error":     fmt.Sprintf("Failed to get file: %v", err),
+		}
+	}
+
+	data, err := decodeFileContent(file)
+	if err != nil {
+		return map[string]interface{}{
+			"file_path": filePath,
+			"error":     fmt.Sprintf("Failed to decode file content: %v", err),
+		}
+	}
+
+	return fileContentResult(file, data, fileReadOptions{MaxBytes: maxBytes})
+}
+
+// resolveFilePatterns はグロブパターンをリポジトリツリーに対して展開し、
+// 重複を除いたパスの一覧を入力順で返す
+func resolveFilePatterns(projectID, ref string, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	trees := make(map[string][]*gitlab.TreeNode)
+	var paths []string
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if !isGlobPattern(pattern) {
+			if !seen[pattern] {
+				seen[pattern] = true
+				paths = append(paths, pattern)
+			}
+			continue
+		}
+
+		// パターンの固定部分のディレクトリだけを再帰的に取得する
+		dir := globBaseDir(pattern)
+		nodes, ok := trees[dir]
+		if !ok {
+			var err error
+			nodes, _, err = listAllTree(projectID, ref, dir, true)
+			if err != nil {
+				return nil, err
+			}
+			trees[dir] = nodes
+		}
+
+		re, err := globToRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if n.Type == "blob" && re.MatchString(n.Path) && !seen[n.Path] {
+				seen[n.Path] = true
+				paths = append(paths, n.Path)
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+func isGlobPattern(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// globBaseDir はグロブ文字を含まない先頭のディレクトリ部分を返す
+func globBaseDir(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	var base []string
+	for _, part := range parts[:len(parts)-1] {
+		if isGlobPattern(part) {
+			break
+		}
+		base = append(base, part)
+	}
+	return strings.Join(base, "/")
+}
+
+// globToRegexp は '*' (区切りを跨がない)、'?'、'**' (任意階層) をサポートするグロブを正規表現に変換する
+func globToRegexp(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			// 閉じられていない [ や空の [] / [!] は文字そのものとして扱う
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			if class == "" || class == "^" {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: malformed character class", pattern)
+	}
+	return re, nil
+}
+
+// fileReadOptions はファイル内容を返す際の行範囲とサイズ上限
+type fileReadOptions struct {
+	StartLine int
+	EndLine   int
+	MaxBytes  int
+}
+
+// decodeFileContent は API から返されたファイル内容をデコードする
+func decodeFileContent(file *gitlab.File) ([]byte, error) {
+	if file.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(file.Content)
+	}
+	return []byte(file.Content), nil
+}
+
+// fileContentResult はファイルのメタデータと、行範囲・サイズ上限を適用した内容を返す。
+// バイナリファイルの場合は内容の代わりにサイズと MIME タイプを返す。
+func fileContentResult(file *gitlab.File, data []byte, opts fileReadOptions) map[string]interface{} {
+	result := map[string]interface{}{
+		"file_name":      file.FileName,
+		"file_path":      file.FilePath,
+		"size":           file.Size,
+		"ref":            file.Ref,
+		"blob_id":        file.BlobID,
+		"commit_id":      file.CommitID,
+		"last_commit_id": file.LastCommitID,
+		"content_sha256": file.SHA256,
+	}
+
+	if isBinary(data) || !utf8.Valid(data) {
+		result["binary"] = true
+		result["mime_type"] = detectMimeType(file.FilePath, data)
+		return result
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	totalLines := len(lines)
+
+	start, end := opts.StartLine, opts.EndLine
+	if start < 1 {
+		start = 1
+	}
+	if end < 1 || end > totalLines {
+		end = totalLines
+	}
+
+	content := ""
+	if start <= end {
+		content = strings.Join(lines[start-1:end], "")
+	}
+
+	truncated := false
+	if opts.MaxBytes > 0 && len(content) > opts.MaxBytes {
+		// マルチバイト文字の途中で切らない
+		cut := opts.MaxBytes
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut] + fmt.Sprintf("\n... (truncated at %d bytes; use start_line/end_line to read more)", cut)
+		truncated = true
+	}
+
+	result["binary"] = false
+	result["content"] = content
+	result["total_lines"] = totalLines
+	result["start_line"] = start
+	result["end_line"] = end
+	result["truncated"] = truncated
+	return result
+}
+
+// detectMimeType は内容を優先し、判別できなければ拡張子から MIME タイプを推定する
+func detectMimeType(filePath string, data []byte) string {
+	mimeType := http.DetectContentType(data)
+	if mimeType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(filePath)); byExt != "" {
+			mimeType = byExt
+		}
+	}
+	return mimeType
+}
+
+func isImageMimeType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
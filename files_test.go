@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestGlobToRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{
+			pattern: "*.go",
+			match:   []string{"main.go", ".go"},
+			noMatch: []string{"cmd/main.go", "main.go.orig"},
+		},
+		{
+			pattern: "src/*/index.ts",
+			match:   []string{"src/app/index.ts"},
+			noMatch: []string{"src/index.ts", "src/a/b/index.ts"},
+		},
+		{
+			pattern: "**/*.md",
+			match:   []string{"README.md", "docs/guide.md", "docs/a/b/c.md"},
+			noMatch: []string{"README.txt"},
+		},
+		{
+			pattern: "docs/**",
+			match:   []string{"docs/a", "docs/a/b/c.md"},
+			noMatch: []string{"doc/a", "src/docs/a"},
+		},
+		{
+			pattern: "file?.txt",
+			match:   []string{"file1.txt", "fileA.txt"},
+			noMatch: []string{"file.txt", "file10.txt", "file/.txt"},
+		},
+		{
+			pattern: "v[0-9].go",
+			match:   []string{"v1.go", "v9.go"},
+			noMatch: []string{"va.go", "v10.go"},
+		},
+		{
+			pattern: "[!_]*.go",
+			match:   []string{"main.go"},
+			noMatch: []string{"_test.go"},
+		},
+		{
+			pattern: "a.b+c(d)$.txt",
+			match:   []string{"a.b+c(d)$.txt"},
+			noMatch: []string{"axb+c(d)$.txt", "a.bbc(d)$.txt"},
+		},
+		{
+			pattern: "weird[name",
+			match:   []string{"weird[name"},
+		},
+		{
+			pattern: "empty[].txt",
+			match:   []string{"empty[].txt"},
+		},
+		{
+			pattern: "neg[!].txt",
+			match:   []string{"neg[!].txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			re, err := globToRegexp(tt.pattern)
+			if err != nil {
+				t.Fatalf("globToRegexp(%q) error: %v", tt.pattern, err)
+			}
+			for _, s := range tt.match {
+				if !re.MatchString(s) {
+					t.Errorf("%q should match %q (regexp %s)", tt.pattern, s, re)
+				}
+			}
+			for _, s := range tt.noMatch {
+				if re.MatchString(s) {
+					t.Errorf("%q should not match %q (regexp %s)", tt.pattern, s, re)
+				}
+			}
+		})
+	}
+}
+
+func TestGlobToRegexpInvalidClass(t *testing.T) {
+	if _, err := globToRegexp(`bad[\].txt`); err == nil {
+		t.Fatal("expected an error for a malformed character class")
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	// アーティファクトアーカイブのダウンロード上限 (デフォルト)
+	defaultMaxArtifactArchiveBytes = 50 * 1024 * 1024
+	// アーカイブから取り出す単一ファイルの返却上限 (デフォルト)
+	defaultMaxArtifactFileBytes = 100 * 1024
+)
+
+func registerJobTools(s *server.MCPServer) {
+	// ジョブ一覧取得
+	s.AddTool(
+		mcp.NewTool("list_jobs",
+			mcp.WithDescription("List CI/CD jobs in a project or in a specific pipeline"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("pipeline_id",
+				mcp.Description("Pipeline ID (if omitted, lists jobs of the whole project)"),
+			),
+			mcp.WithString("scope",
+				mcp.Description("Comma-separated list of job states: created, pending, running, failed, success, canceled, skipped, manual"),
+			),
+			mcp.WithBoolean("include_retried",
+				mcp.Description("Include retried jobs (pipeline jobs only, default: false)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of jobs per page (default: 20)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleListJobs,
+	)
+
+	// ジョブのリトライ
+	s.AddTool(
+		mcp.NewTool("retry_job",
+			mcp.WithDescription("Retry a CI/CD job"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("job_id",
+				mcp.Required(),
+				mcp.Description("Job ID"),
+			),
+		),
+		handleRetryJob,
+	)
+
+	// ジョブのキャンセル
+	s.AddTool(
+		mcp.NewTool("cancel_job",
+			mcp.WithDescription("Cancel a running or pending CI/CD job"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("job_id",
+				mcp.Required(),
+				mcp.Description("Job ID"),
+			),
+		),
+		handleCancelJob,
+	)
+
+	// 手動ジョブの実行
+	s.AddTool(
+		mcp.NewTool("play_job",
+			mcp.WithDescription("Trigger a manual CI/CD job, optionally with job variables"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("job_id",
+				mcp.Required(),
+				mcp.Description("Job ID"),
+			),
+			mcp.WithArray("variables",
+				mcp.Description("Array of variable objects with 'key', 'value' and optional 'variable_type' (env_var or file)"),
+			),
+		),
+		handlePlayJob,
+	)
+
+	// ジョブアーティファクトの取得
+	s.AddTool(
+		mcp.NewTool("get_job_artifact",
+			mcp.WithDescription("List files in a job's artifacts archive, or extract a single file from it"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("job_id",
+				mcp.Required(),
+				mcp.Description("Job ID"),
+			),
+			mcp.WithString("artifact_path",
+				mcp.Description("Path of the file inside the archive (if omitted, lists archive entries)"),
+			),
+			mcp.WithNumber("max_bytes",
+				mcp.Description("Maximum number of bytes of the extracted file to return (default: 102400)"),
+			),
+			mcp.WithNumber("max_archive_bytes",
+				mcp.Description("Refuse to download archives larger than this (default: 52428800)"),
+			),
+		),
+		handleGetJobArtifact,
+	)
+}
+
+func handleListJobs(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	pipelineID := getInt(args, "pipeline_id", 0)
+	perPage := getInt(args, "per_page", 20)
+	page := getInt(args, "page", 1)
+
+	opts := &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		},
+	}
+
+	if scope := getString(args, "scope", ""); scope != "" {
+		var states []gitlab.BuildStateValue
+		for _, s := range splitLabels(scope) {
+			states = append(states, gitlab.BuildStateValue(s))
+		}
+		opts.Scope = &states
+	}
+
+	if includeRetried, ok := args["include_retried"].(bool); ok {
+		opts.IncludeRetried = gitlab.Ptr(includeRetried)
+	}
+
+	var jobs []*gitlab.Job
+	var err error
+	if pipelineID > 0 {
+		jobs, _, err = gitlabClient.Jobs.ListPipelineJobs(projectID, pipelineID, opts)
+	} else {
+		jobs, _, err = gitlabClient.Jobs.ListProjectJobs(projectID, opts)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list jobs: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(jobs))
+	for i, j := range jobs {
+		result[i] = jobSummary(j)
+	}
+
+	return jsonResult(result)
+}
+
+func handleRetryJob(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	jobID := getInt(args, "job_id", 0)
+	if jobID <= 0 {
+		return mcp.NewToolResultError("job_id is required"), nil
+	}
+
+	job, _, err := gitlabClient.Jobs.RetryJob(projectID, jobID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to retry job: %v", err)), nil
+	}
+
+	return jsonResult(jobSummary(job))
+}
+
+func handleCancelJob(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	jobID := getInt(args, "job_id", 0)
+	if jobID <= 0 {
+		return mcp.NewToolResultError("job_id is required"), nil
+	}
+
+	job, _, err := gitlabClient.Jobs.CancelJob(projectID, jobID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to cancel job: %v", err)), nil
+	}
+
+	return jsonResult(jobSummary(job))
+}
+
+func handlePlayJob(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	jobID := getInt(args, "job_id", 0)
+	if jobID <= 0 {
+		return mcp.NewToolResultError("job_id is required"), nil
+	}
+
+	opts := &gitlab.PlayJobOptions{}
+
+	if varsArg, ok := args["variables"].([]interface{}); ok && len(varsArg) > 0 {
+		var variables []*gitlab.JobVariableOptions
+		for _, v := range varsArg {
+			varMap, ok := v.(map[string]interface{})
+			if !ok {
+				return mcp.NewToolResultError("each variable must be an object with 'key' and 'value' fields"), nil
+			}
+
+			key, ok := varMap["key"].(string)
+			if !ok || key == "" {
+				return mcp.NewToolResultError("each variable must have a 'key' field"), nil
+			}
+
+			variable := &gitlab.JobVariableOptions{
+				Key:   gitlab.Ptr(key),
+				Value: gitlab.Ptr(getString(varMap, "value", "")),
+			}
+			if varType := getString(varMap, "variable_type", ""); varType != "" {
+				variable.VariableType = gitlab.Ptr(gitlab.VariableTypeValue(varType))
+			}
+			variables = append(variables, variable)
+		}
+		opts.JobVariablesAttributes = &variables
+	}
+
+	job, _, err := gitlabClient.Jobs.PlayJob(projectID, jobID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to play job: %v", err)), nil
+	}
+
+	return jsonResult(jobSummary(job))
+}
+
+func handleGetJobArtifact(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	jobID := getInt(args, "job_id", 0)
+	if jobID <= 0 {
+		return mcp.NewToolResultError("job_id is required"), nil
+	}
+
+	artifactPath := getString(args, "artifact_path", "")
+	maxBytes := getInt(args, "max_bytes", defaultMaxArtifactFileBytes)
+	maxArchiveBytes := getInt(args, "max_archive_bytes", defaultMaxArtifactArchiveBytes)
+	if maxBytes < 1 || maxArchiveBytes < 1 {
+		return mcp.NewToolResultError("max_bytes and max_archive_bytes must be at least 1"), nil
+	}
+
+	// ダウンロード前にアーカイブサイズを確認
+	job, _, err := gitlabClient.Jobs.GetJob(projectID, jobID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get job: %v", err)), nil
+	}
+	if job.ArtifactsFile.Filename == "" {
+		return mcp.NewToolResultError(fmt.Sprintf("Job %d has no artifacts archive", jobID)), nil
+	}
+	if job.ArtifactsFile.Size > maxArchiveBytes {
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"Artifacts archive is %d bytes, which exceeds max_archive_bytes (%d)",
+			job.ArtifactsFile.Size, maxArchiveBytes,
+		)), nil
+	}
+
+	archive, _, err := gitlabClient.Jobs.GetJobArtifacts(projectID, jobID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to download artifacts: %v", err)), nil
+	}
+
+	zr, err := zip.NewReader(archive, archive.Size())
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to read artifacts archive: %v", err)), nil
+	}
+
+	// パス未指定の場合はアーカイブ内のファイル一覧を返す
+	if artifactPath == "" {
+		entries := make([]map[string]interface{}, 0, len(zr.File))
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			entries = append(entries, map[string]interface{}{
+				"path": f.Name,
+				"size": f.UncompressedSize64,
+			})
+		}
+
+		result := map[string]interface{}{
+			"job_id":       jobID,
+			"archive":      job.ArtifactsFile.Filename,
+			"archive_size": job.ArtifactsFile.Size,
+			"files":        entries,
+			"files_count":  len(entries),
+		}
+		return jsonResult(result)
+	}
+
+	for _, f := range zr.File {
+		if f.Name != artifactPath {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to open artifact file: %v", err)), nil
+		}
+
+		// 上限 + 1 バイトだけ読み込み、切り詰めが発生したかを判定する
+		var buf bytes.Buffer
+		_, err = io.CopyN(&buf, rc, int64(maxBytes)+1)
+		rc.Close()
+		if err != nil && err != io.EOF {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to extract artifact file: %v", err)), nil
+		}
+
+		data := buf.Bytes()
+		truncated := len(data) > maxBytes
+		if truncated {
+			data = data[:maxBytes]
+		}
+
+		result := map[string]interface{}{
+			"job_id":    jobID,
+			"path":      f.Name,
+			"size":      f.UncompressedSize64,
+			"truncated": truncated,
+		}
+		if isBinary(data) {
+			result["encoding"] = "base64"
+			result["content"] = base64.StdEncoding.EncodeToString(data)
+		} else {
+			result["encoding"] = "text"
+			result["content"] = string(data)
+		}
+		return jsonResult(result)
+	}
+
+	return mcp.NewToolResultError(fmt.Sprintf("File %q not found in artifacts archive", artifactPath)), nil
+}
+
+func jobSummary(j *gitlab.Job) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             j.ID,
+		"name":           j.Name,
+		"stage":          j.Stage,
+		"status":         j.Status,
+		"ref":            j.Ref,
+		"pipeline_id":    j.Pipeline.ID,
+		"allow_failure":  j.AllowFailure,
+		"failure_reason": j.FailureReason,
+		"duration":       j.Duration,
+		"coverage":       j.Coverage,
+		"created_at":     j.CreatedAt,
+		"finished_at":    j.FinishedAt,
+		"web_url":        j.WebURL,
+	}
+}
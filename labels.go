@@ -0,0 +1,402 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+func registerLabelTools(s *server.MCPServer) {
+	// ラベル一覧取得
+	s.AddTool(
+		mcp.NewTool("list_labels",
+			mcp.WithDescription("List labels of a project or group, including priority and whether they are scoped (scope::value)"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Search labels by name"),
+			),
+			mcp.WithBoolean("with_counts",
+				mcp.Description("Include open/closed issue and open merge request counts (default: false)"),
+			),
+			mcp.WithBoolean("include_ancestor_groups",
+				mcp.Description("Include labels inherited from ancestor groups (default: true)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of labels per page (default: 100)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleListLabels,
+	)
+
+	// ラベル作成
+	s.AddTool(
+		mcp.NewTool("create_label",
+			mcp.WithDescription("Create a label in a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("name",
+				mcp.Required(),
+				mcp.Description("Label name; use 'scope::value' for a scoped label"),
+			),
+			mcp.WithString("color",
+				mcp.Required(),
+				mcp.Description("Color as '#RRGGBB' or a CSS color name"),
+			),
+			mcp.WithString("description",
+				mcp.Description("Label description"),
+			),
+			mcp.WithNumber("priority",
+				mcp.Description("Label priority; lower numbers are higher priority"),
+			),
+		),
+		handleCreateLabel,
+	)
+
+	// ラベル更新
+	s.AddTool(
+		mcp.NewTool("update_label",
+			mcp.WithDescription("Update a label in a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("name",
+				mcp.Required(),
+				mcp.Description("Current label name or ID"),
+			),
+			mcp.WithString("new_name",
+				mcp.Description("New label name"),
+			),
+			mcp.WithString("color",
+				mcp.Description("New color as '#RRGGBB' or a CSS color name"),
+			),
+			mcp.WithString("description",
+				mcp.Description("New description"),
+			),
+			mcp.WithNumber("priority",
+				mcp.Description("New priority; lower numbers are higher priority"),
+			),
+		),
+		handleUpdateLabel,
+	)
+
+	// ラベル削除
+	s.AddTool(
+		mcp.NewTool("delete_label",
+			mcp.WithDescription("Delete a label from a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("name",
+				mcp.Required(),
+				mcp.Description("Label name or ID"),
+			),
+		),
+		handleDeleteLabel,
+	)
+}
+
+func handleListLabels(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	listOpts := gitlab.ListOptions{
+		PerPage: getInt(args, "per_page", 100),
+		Page:    getInt(args, "page", 1),
+	}
+	withCounts := getBool(args, "with_counts", false)
+	includeAncestors := getBool(args, "include_ancestor_groups", true)
+	var search *string
+	if v := getString(args, "search", ""); v != "" {
+		search = gitlab.Ptr(v)
+	}
+
+	var labels []*gitlab.Label
+	if projectID != "" {
+		l, _, err := gitlabClient.Labels.ListLabels(projectID, &gitlab.ListLabelsOptions{
+			ListOptions:           listOpts,
+			WithCounts:            gitlab.Ptr(withCounts),
+			IncludeAncestorGroups: gitlab.Ptr(includeAncestors),
+			Search:                search,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list labels: %v", err)), nil
+		}
+		labels = l
+	} else {
+		l, _, err := gitlabClient.GroupLabels.ListGroupLabels(groupID, &gitlab.ListGroupLabelsOptions{
+			ListOptions:           listOpts,
+			WithCounts:            gitlab.Ptr(withCounts),
+			IncludeAncestorGroups: gitlab.Ptr(includeAncestors),
+			Search:                search,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list labels: %v", err)), nil
+		}
+		for _, label := range l {
+			labels = append(labels, (*gitlab.Label)(label))
+		}
+	}
+
+	result := make([]map[string]interface{}, len(labels))
+	for i, l := range labels {
+		result[i] = labelSummary(l, withCounts)
+	}
+
+	return jsonResult(result)
+}
+
+func handleCreateLabel(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+
+	color, ok := args["color"].(string)
+	if !ok || color == "" {
+		return mcp.NewToolResultError("color is required"), nil
+	}
+
+	var description *string
+	if v := getString(args, "description", ""); v != "" {
+		description = gitlab.Ptr(v)
+	}
+	var priority *int
+	if v, ok := args["priority"].(float64); ok {
+		priority = gitlab.Ptr(int(v))
+	}
+
+	var label *gitlab.Label
+	if projectID != "" {
+		l, _, err := gitlabClient.Labels.CreateLabel(projectID, &gitlab.CreateLabelOptions{
+			Name:        gitlab.Ptr(name),
+			Color:       gitlab.Ptr(color),
+			Description: description,
+			Priority:    priority,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create label: %v", err)), nil
+		}
+		label = l
+	} else {
+		l, _, err := gitlabClient.GroupLabels.CreateGroupLabel(groupID, &gitlab.CreateGroupLabelOptions{
+			Name:        gitlab.Ptr(name),
+			Color:       gitlab.Ptr(color),
+			Description: description,
+			Priority:    priority,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create label: %v", err)), nil
+		}
+		label = (*gitlab.Label)(l)
+	}
+
+	return jsonResult(labelSummary(label, false))
+}
+
+func handleUpdateLabel(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+
+	var newName, color, description *string
+	if v := getString(args, "new_name", ""); v != "" {
+		newName = gitlab.Ptr(v)
+	}
+	if v := getString(args, "color", ""); v != "" {
+		color = gitlab.Ptr(v)
+	}
+	if v, ok := args["description"].(string); ok {
+		description = gitlab.Ptr(v)
+	}
+	var priority *int
+	if v, ok := args["priority"].(float64); ok {
+		priority = gitlab.Ptr(int(v))
+	}
+
+	var label *gitlab.Label
+	if projectID != "" {
+		l, _, err := gitlabClient.Labels.UpdateLabel(projectID, name, &gitlab.UpdateLabelOptions{
+			NewName:     newName,
+			Color:       color,
+			Description: description,
+			Priority:    priority,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to update label: %v", err)), nil
+		}
+		label = l
+	} else {
+		l, _, err := gitlabClient.GroupLabels.UpdateGroupLabel(groupID, name, &gitlab.UpdateGroupLabelOptions{
+			NewName:     newName,
+			Color:       color,
+			Description: description,
+			Priority:    priority,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to update label: %v", err)), nil
+		}
+		label = (*gitlab.Label)(l)
+	}
+
+	return jsonResult(labelSummary(label, false))
+}
+
+func handleDeleteLabel(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return mcp.NewToolResultError("name is required"), nil
+	}
+
+	var err error
+	if projectID != "" {
+		_, err = gitlabClient.Labels.DeleteLabel(projectID, name, nil)
+	} else {
+		_, err = gitlabClient.GroupLabels.DeleteGroupLabel(groupID, name, nil)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete label: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action": "deleted",
+		"name":   name,
+	}
+	return jsonResult(result)
+}
+
+// validateLabels はラベルがプロジェクト (および上位グループ) に存在すること、
+// 同じスコープのスコープ付きラベルが複数指定されていないことを確認する
+func validateLabels(projectID string, labels []string) error {
+	opts := &gitlab.ListLabelsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		IncludeAncestorGroups: gitlab.Ptr(true),
+	}
+
+	known := make(map[string]bool)
+	var names []string
+	for {
+		page, resp, err := gitlabClient.Labels.ListLabels(projectID, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list labels: %v", err)
+		}
+		for _, l := range page {
+			known[l.Name] = true
+			names = append(names, l.Name)
+		}
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	var problems []string
+	scopes := make(map[string]string)
+	for _, label := range labels {
+		if !known[label] {
+			problem := fmt.Sprintf("unknown label %q", label)
+			// 大文字小文字違いの既存ラベルがあれば候補として示す
+			for _, name := range names {
+				if strings.EqualFold(name, label) {
+					problem += fmt.Sprintf(" (did you mean %q?)", name)
+					break
+				}
+			}
+			problems = append(problems, problem)
+		}
+
+		if scope := labelScope(label); scope != "" {
+			if other, ok := scopes[scope]; ok {
+				problems = append(problems, fmt.Sprintf("scoped labels %q and %q are mutually exclusive", other, label))
+				continue
+			}
+			scopes[scope] = label
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid labels: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// labelScope はスコープ付きラベル (scope::value) のスコープを返す。
+// ネストしたスコープ (a::b::c) では最後の "::" より前がスコープになる。
+func labelScope(label string) string {
+	i := strings.LastIndex(label, "::")
+	if i <= 0 {
+		return ""
+	}
+	return label[:i]
+}
+
+func labelSummary(l *gitlab.Label, withCounts bool) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":               l.ID,
+		"name":             l.Name,
+		"color":            l.Color,
+		"description":      l.Description,
+		"priority":         l.Priority,
+		"is_project_label": l.IsProjectLabel,
+	}
+	if scope := labelScope(l.Name); scope != "" {
+		result["scope"] = scope
+	}
+	if withCounts {
+		result["open_issues_count"] = l.OpenIssuesCount
+		result["closed_issues_count"] = l.ClosedIssuesCount
+		result["open_merge_requests_count"] = l.OpenMergeRequestsCount
+	}
+	return result
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -28,6 +33,9 @@ func main() {
 		baseURL = "https://gitlab.com"
 	}
 
+	// マスク対象の CI/CD 変数の値を返すかどうか (デフォルト: 返さない)
+	revealMaskedVariables = os.Getenv("GITLAB_MCP_REVEAL_MASKED_VARIABLES") == "true"
+
 	var err error
 	gitlabClient, err = gitlab.NewClient(token, gitlab.WithBaseURL(baseURL+"/api/v4"))
 	if err != nil {
@@ -115,6 +123,12 @@ func registerTools(s *server.MCPServer) {
 			mcp.WithString("labels",
 				mcp.Description("Comma-separated list of labels"),
 			),
+			mcp.WithNumber("milestone_id",
+				mcp.Description("ID of the milestone to assign"),
+			),
+			mcp.WithBoolean("validate_labels",
+				mcp.Description("Reject labels that do not exist in the project and more than one scoped label (scope::value) per scope (default: false)"),
+			),
 		),
 		handleCreateIssue,
 	)
@@ -172,6 +186,12 @@ func registerTools(s *server.MCPServer) {
 			mcp.WithString("assignee_ids",
 				mcp.Description("Comma-separated list of assignee user IDs"),
 			),
+			mcp.WithNumber("milestone_id",
+				mcp.Description("ID of the milestone to assign"),
+			),
+			mcp.WithBoolean("validate_labels",
+				mcp.Description("Reject labels that do not exist in the project and more than one scoped label (scope::value) per scope (default: false)"),
+			),
 		),
 		handleCreateMergeRequest,
 	)
@@ -191,6 +211,18 @@ func registerTools(s *server.MCPServer) {
 			mcp.WithString("ref",
 				mcp.Description("Branch, tag, or commit SHA (default: default branch)"),
 			),
+			mcp.WithNumber("start_line",
+				mcp.Description("First line to return, 1-based (default: 1)"),
+			),
+			mcp.WithNumber("end_line",
+				mcp.Description("Last line to return, inclusive (default: last line)"),
+			),
+			mcp.WithNumber("max_bytes",
+				mcp.Description("Maximum bytes of content to return before truncating (default: 102400)"),
+			),
+			mcp.WithBoolean("raw",
+				mcp.Description("Return only the file content as plain text, without metadata (default: false)"),
+			),
 		),
 		handleGetFile,
 	)
@@ -225,6 +257,15 @@ func registerTools(s *server.MCPServer) {
 			mcp.WithString("author_name",
 				mcp.Description("Author name for the commit"),
 			),
+			mcp.WithString("mode",
+				mcp.Description("create (fail if the file exists), update (fail if it does not) or upsert (default: upsert)"),
+			),
+			mcp.WithString("expected_last_commit_id",
+				mcp.Description("Last commit ID that touched the file when it was read; the update fails with a conflict if the file changed since"),
+			),
+			mcp.WithString("expected_blob_sha",
+				mcp.Description("Blob SHA of the file when it was read; the update fails with a conflict if the file changed since"),
+			),
 		),
 		handleCreateOrUpdateFile,
 	)
@@ -315,7 +356,7 @@ func registerTools(s *server.MCPServer) {
 			),
 			mcp.WithArray("files",
 				mcp.Required(),
-				mcp.Description("Array of file objects with 'path' and 'content' fields"),
+				mcp.Description("Array of file objects with 'path' and 'content' fields. Optional fields: 'action' (create, update, delete, move, chmod; default: create or update depending on whether the file exists), 'previous_path' (for move), 'encoding' (text or base64), 'execute_filemode' (boolean)"),
 			),
 			mcp.WithString("author_email",
 				mcp.Description("Author email for the commit"),
@@ -323,9 +364,60 @@ func registerTools(s *server.MCPServer) {
 			mcp.WithString("author_name",
 				mcp.Description("Author name for the commit"),
 			),
+			mcp.WithBoolean("validate_ci",
+				mcp.Description("Lint the project's CI config file if it is among the pushed files, and refuse to commit when it is invalid (default: false)"),
+			),
+			mcp.WithString("start_branch",
+				mcp.Description("Create 'branch' from this branch if it does not exist yet"),
+			),
+			mcp.WithString("start_sha",
+				mcp.Description("Create 'branch' from this commit SHA if it does not exist yet"),
+			),
+			mcp.WithBoolean("force",
+				mcp.Description("Overwrite 'branch' with a new commit based on start_branch or start_sha, discarding its current history (default: false)"),
+			),
 		),
 		handlePushFiles,
 	)
+
+	// CI/CD ジョブ関連ツール
+	registerJobTools(s)
+
+	// パイプライン関連ツール
+	registerPipelineTools(s)
+
+	// 環境・デプロイ関連ツール
+	registerEnvironmentTools(s)
+
+	// CI/CD 変数関連ツール
+	registerVariableTools(s)
+
+	// リポジトリ閲覧ツール
+	registerRepositoryTools(s)
+
+	// 検索ツール
+	registerSearchTools(s)
+
+	// ファイル一括取得ツール
+	registerFileTools(s)
+
+	// パッチ適用ツール
+	registerPatchTools(s)
+
+	// コミット履歴ツール
+	registerCommitTools(s)
+
+	// ブランチ管理ツール
+	registerBranchTools(s)
+
+	// タグ・リリースツール
+	registerReleaseTools(s)
+
+	// マイルストーン・イテレーションツール
+	registerMilestoneTools(s)
+
+	// ラベル管理ツール
+	registerLabelTools(s)
 }
 
 // ツールハンドラー
@@ -452,10 +544,19 @@ func handleCreateIssue(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	}
 
 	if labels := getString(args, "labels", ""); labels != "" {
+		if getBool(args, "validate_labels", false) {
+			if err := validateLabels(projectID, splitLabels(labels)); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
 		labelList := gitlab.LabelOptions(splitLabels(labels))
 		opts.Labels = &labelList
 	}
 
+	if milestoneID := getInt(args, "milestone_id", 0); milestoneID > 0 {
+		opts.MilestoneID = gitlab.Ptr(milestoneID)
+	}
+
 	issue, _, err := gitlabClient.Issues.CreateIssue(projectID, opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to create issue: %v", err)), nil
@@ -550,6 +651,11 @@ func handleCreateMergeRequest(ctx context.Context, req mcp.CallToolRequest) (*mc
 	}
 
 	if labels := getString(args, "labels", ""); labels != "" {
+		if getBool(args, "validate_labels", false) {
+			if err := validateLabels(projectID, splitLabels(labels)); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
 		labelList := gitlab.LabelOptions(splitLabels(labels))
 		opts.Labels = &labelList
 	}
@@ -561,6 +667,10 @@ func handleCreateMergeRequest(ctx context.Context, req mcp.CallToolRequest) (*mc
 		}
 	}
 
+	if milestoneID := getInt(args, "milestone_id", 0); milestoneID > 0 {
+		opts.MilestoneID = gitlab.Ptr(milestoneID)
+	}
+
 	mr, _, err := gitlabClient.MergeRequests.CreateMergeRequest(projectID, opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to create merge request: %v", err)), nil
@@ -602,23 +712,31 @@ func handleGetFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to get file: %v", err)), nil
 	}
 
-	var content string
-	if file.Encoding == "base64" {
-		decoded, err := base64.StdEncoding.DecodeString(file.Content)
+	data, err := decodeFileContent(file)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode file content: %v", err)), nil
+	}
+
+	readOpts := fileReadOptions{
+		StartLine: getInt(args, "start_line", 0),
+		EndLine:   getInt(args, "end_line", 0),
+		MaxBytes:  getInt(args, "max_bytes", defaultMaxFileBytes),
+	}
+	result := fileContentResult(file, data, readOpts)
+
+	// 画像はテキストではなく MCP の画像コンテンツとして返す
+	if mimeType, _ := result["mime_type"].(string); result["binary"] == true && isImageMimeType(mimeType) && len(data) <= readOpts.MaxBytes {
+		jsonBytes, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to decode file content: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal result: %v", err)), nil
 		}
-		content = string(decoded)
-	} else {
-		content = file.Content
+		return mcp.NewToolResultImage(string(jsonBytes), base64.StdEncoding.EncodeToString(data), mimeType), nil
 	}
 
-	result := map[string]interface{}{
-		"file_name": file.FileName,
-		"file_path": file.FilePath,
-		"size":      file.Size,
-		"ref":       file.Ref,
-		"content":   content,
+	if getBool(args, "raw", false) {
+		if content, ok := result["content"].(string); ok {
+			return mcp.NewToolResultText(content), nil
+		}
 	}
 
 	return jsonResult(result)
@@ -651,40 +769,42 @@ func handleCreateOrUpdateFile(ctx context.Context, req mcp.CallToolRequest) (*mc
 		return mcp.NewToolResultError("commit_message is required"), nil
 	}
 
-	// ファイルが存在するかチェック
-	_, resp, err := gitlabClient.RepositoryFiles.GetFile(projectID, filePath, &gitlab.GetFileOptions{
-		Ref: gitlab.Ptr(branch),
-	})
+	mode := getString(args, "mode", "upsert")
+	expectedLastCommitID := getString(args, "expected_last_commit_id", "")
+	expectedBlobSHA := getString(args, "expected_blob_sha", "")
 
-	fileExists := err == nil && resp.StatusCode == 200
+	switch mode {
+	case "create", "update", "upsert":
+	default:
+		return mcp.NewToolResultError("mode must be one of: create, update, upsert"), nil
+	}
 
-	if fileExists {
-		// ファイル更新
-		opts := &gitlab.UpdateFileOptions{
-			Branch:        gitlab.Ptr(branch),
-			Content:       gitlab.Ptr(content),
-			CommitMessage: gitlab.Ptr(commitMessage),
-		}
+	// 期待値が指定されていれば既存ファイルの更新として扱う
+	if mode == "upsert" && (expectedLastCommitID != "" || expectedBlobSHA != "") {
+		mode = "update"
+	}
 
-		if authorEmail := getString(args, "author_email", ""); authorEmail != "" {
-			opts.AuthorEmail = gitlab.Ptr(authorEmail)
-		}
-		if authorName := getString(args, "author_name", ""); authorName != "" {
-			opts.AuthorName = gitlab.Ptr(authorName)
-		}
+	if mode == "create" && (expectedLastCommitID != "" || expectedBlobSHA != "") {
+		return mcp.NewToolResultError("expected_last_commit_id and expected_blob_sha cannot be used with mode 'create'"), nil
+	}
 
-		fileResp, _, err := gitlabClient.RepositoryFiles.UpdateFile(projectID, filePath, opts)
-		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to update file: %v", err)), nil
-		}
+	if mode == "upsert" {
+		// ファイルが存在するかチェック (内容をダウンロードしないようメタデータのみ取得)
+		_, _, err := gitlabClient.RepositoryFiles.GetFileMetaData(projectID, filePath, &gitlab.GetFileMetaDataOptions{
+			Ref: gitlab.Ptr(branch),
+		})
 
-		result := map[string]interface{}{
-			"action":    "updated",
-			"file_path": fileResp.FilePath,
-			"branch":    fileResp.Branch,
+		switch {
+		case err == nil:
+			mode = "update"
+		case isNotFoundError(err):
+			mode = "create"
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get file metadata: %v", err)), nil
 		}
-		return jsonResult(result)
-	} else {
+	}
+
+	if mode == "create" {
 		// ファイル作成
 		opts := &gitlab.CreateFileOptions{
 			Branch:        gitlab.Ptr(branch),
@@ -711,6 +831,60 @@ func handleCreateOrUpdateFile(ctx context.Context, req mcp.CallToolRequest) (*mc
 		}
 		return jsonResult(result)
 	}
+
+	// ファイル更新
+	opts := &gitlab.UpdateFileOptions{
+		Branch:        gitlab.Ptr(branch),
+		Content:       gitlab.Ptr(content),
+		CommitMessage: gitlab.Ptr(commitMessage),
+	}
+
+	if authorEmail := getString(args, "author_email", ""); authorEmail != "" {
+		opts.AuthorEmail = gitlab.Ptr(authorEmail)
+	}
+	if authorName := getString(args, "author_name", ""); authorName != "" {
+		opts.AuthorName = gitlab.Ptr(authorName)
+	}
+
+	if expectedBlobSHA != "" {
+		current, _, err := gitlabClient.RepositoryFiles.GetFileMetaData(projectID, filePath, &gitlab.GetFileMetaDataOptions{
+			Ref: gitlab.Ptr(branch),
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get file metadata: %v", err)), nil
+		}
+		if current.BlobID != expectedBlobSHA {
+			return fileConflictResult(filePath, branch, expectedLastCommitID, expectedBlobSHA, current), nil
+		}
+		// 確認後の更新との競合も GitLab 側で検出させる
+		if expectedLastCommitID == "" {
+			expectedLastCommitID = current.LastCommitID
+		}
+	}
+
+	if expectedLastCommitID != "" {
+		opts.LastCommitID = gitlab.Ptr(expectedLastCommitID)
+	}
+
+	fileResp, _, err := gitlabClient.RepositoryFiles.UpdateFile(projectID, filePath, opts)
+	if err != nil {
+		if expectedLastCommitID != "" && isFileChangedError(err) {
+			current, _, metaErr := gitlabClient.RepositoryFiles.GetFileMetaData(projectID, filePath, &gitlab.GetFileMetaDataOptions{
+				Ref: gitlab.Ptr(branch),
+			})
+			if metaErr == nil {
+				return fileConflictResult(filePath, branch, expectedLastCommitID, expectedBlobSHA, current), nil
+			}
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to update file: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action":    "updated",
+		"file_path": fileResp.FilePath,
+		"branch":    fileResp.Branch,
+	}
+	return jsonResult(result)
 }
 
 func handleDeleteFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -856,8 +1030,45 @@ func handlePushFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 		return mcp.NewToolResultError("files is required and must be a non-empty array"), nil
 	}
 
+	startBranch := getString(args, "start_branch", "")
+	startSHA := getString(args, "start_sha", "")
+	force := getBool(args, "force", false)
+
+	if startBranch != "" && startSHA != "" {
+		return mcp.NewToolResultError("only one of start_branch or start_sha can be specified"), nil
+	}
+	if force && startBranch == "" && startSHA == "" {
+		return mcp.NewToolResultError("force requires start_branch or start_sha"), nil
+	}
+
+	start := time.Now()
+
+	// ファイルの存在判定に使う ref (新規ブランチや強制上書きの場合は起点)
+	baseRef := branch
+	branchCreated := false
+	if startBranch != "" || startSHA != "" {
+		startRef := startBranch
+		if startSHA != "" {
+			startRef = startSHA
+		}
+
+		// 強制上書きでもブランチがなければ作成されるため、branch_created のために存在を確認する
+		_, _, err := gitlabClient.Branches.GetBranch(projectID, branch)
+		if err != nil {
+			if !isNotFoundError(err) {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to get branch: %v", err)), nil
+			}
+			baseRef = startRef
+			branchCreated = true
+		}
+		if force {
+			baseRef = startRef
+		}
+	}
+
 	// CommitActionsを構築
 	var actions []*gitlab.CommitActionOptions
+	var autoPaths []string
 	for _, f := range filesArg {
 		fileMap, ok := f.(map[string]interface{})
 		if !ok {
@@ -869,28 +1080,99 @@ func handlePushFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 			return mcp.NewToolResultError("each file must have a 'path' field"), nil
 		}
 
-		content, ok := fileMap["content"].(string)
-		if !ok {
-			return mcp.NewToolResultError("each file must have a 'content' field"), nil
+		commitAction := &gitlab.CommitActionOptions{
+			FilePath: gitlab.Ptr(filePath),
 		}
 
-		// ファイルが存在するかチェックしてアクションを決定
-		_, resp, err := gitlabClient.RepositoryFiles.GetFile(projectID, filePath, &gitlab.GetFileOptions{
-			Ref: gitlab.Ptr(branch),
-		})
+		content, hasContent := fileMap["content"].(string)
+		if hasContent {
+			commitAction.Content = gitlab.Ptr(content)
+		}
 
-		var action gitlab.FileActionValue
-		if err == nil && resp.StatusCode == 200 {
-			action = gitlab.FileUpdate
-		} else {
-			action = gitlab.FileCreate
+		encoding := getString(fileMap, "encoding", "text")
+		switch encoding {
+		case "text":
+		case "base64":
+			if _, err := base64.StdEncoding.DecodeString(content); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s: content is not valid base64: %v", filePath, err)), nil
+			}
+			commitAction.Encoding = gitlab.Ptr(encoding)
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("%s: encoding must be 'text' or 'base64'", filePath)), nil
 		}
 
-		actions = append(actions, &gitlab.CommitActionOptions{
-			Action:   gitlab.Ptr(action),
-			FilePath: gitlab.Ptr(filePath),
-			Content:  gitlab.Ptr(content),
-		})
+		if executeFilemode, ok := fileMap["execute_filemode"].(bool); ok {
+			commitAction.ExecuteFilemode = gitlab.Ptr(executeFilemode)
+		}
+
+		action := gitlab.FileActionValue(getString(fileMap, "action", ""))
+		switch action {
+		case "":
+			if !hasContent {
+				return mcp.NewToolResultError("each file must have a 'content' field"), nil
+			}
+			// create / update は後でまとめて判定する
+			autoPaths = append(autoPaths, filePath)
+
+		case gitlab.FileCreate, gitlab.FileUpdate:
+			if !hasContent {
+				return mcp.NewToolResultError(fmt.Sprintf("%s: 'content' is required for action '%s'", filePath, action)), nil
+			}
+
+		case gitlab.FileDelete:
+			commitAction.Content = nil
+			commitAction.Encoding = nil
+
+		case gitlab.FileMove:
+			previousPath := getString(fileMap, "previous_path", "")
+			if previousPath == "" {
+				return mcp.NewToolResultError(fmt.Sprintf("%s: 'previous_path' is required for action 'move'", filePath)), nil
+			}
+			commitAction.PreviousPath = gitlab.Ptr(previousPath)
+
+		case gitlab.FileChmod:
+			if commitAction.ExecuteFilemode == nil {
+				return mcp.NewToolResultError(fmt.Sprintf("%s: 'execute_filemode' is required for action 'chmod'", filePath)), nil
+			}
+
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("%s: action must be one of create, update, delete, move, chmod", filePath)), nil
+		}
+
+		if action != "" {
+			commitAction.Action = gitlab.Ptr(action)
+		}
+		actions = append(actions, commitAction)
+	}
+
+	// アクション未指定のファイルはディレクトリ単位のツリー取得で存在を判定する
+	// (ファイルごとに内容をダウンロードしない)
+	classifyRequests := 0
+	if len(autoPaths) > 0 {
+		existing, requests, err := existingPaths(projectID, baseRef, autoPaths)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list repository tree: %v", err)), nil
+		}
+		classifyRequests = requests
+
+		for _, a := range actions {
+			if a.Action != nil {
+				continue
+			}
+			if existing[*a.FilePath] {
+				a.Action = gitlab.Ptr(gitlab.FileUpdate)
+			} else {
+				a.Action = gitlab.Ptr(gitlab.FileCreate)
+			}
+		}
+	}
+	classifyDuration := time.Since(start)
+
+	// CI 設定ファイルが含まれていればコミット前に検証
+	if getBool(args, "validate_ci", false) {
+		if errResult := validateCIConfigInActions(projectID, baseRef, actions); errResult != nil {
+			return errResult, nil
+		}
 	}
 
 	// コミットオプションを構築
@@ -900,6 +1182,19 @@ func handlePushFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 		Actions:       actions,
 	}
 
+	// 起点はブランチを作成する場合と強制上書きの場合のみ送る
+	if branchCreated || force {
+		if startBranch != "" {
+			opts.StartBranch = gitlab.Ptr(startBranch)
+		}
+		if startSHA != "" {
+			opts.StartSHA = gitlab.Ptr(startSHA)
+		}
+	}
+	if force {
+		opts.Force = gitlab.Ptr(true)
+	}
+
 	if authorEmail := getString(args, "author_email", ""); authorEmail != "" {
 		opts.AuthorEmail = gitlab.Ptr(authorEmail)
 	}
@@ -908,29 +1203,131 @@ func handlePushFiles(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 	}
 
 	// コミットを作成
+	commitStart := time.Now()
 	commit, _, err := gitlabClient.Commits.CreateCommit(projectID, opts)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to push files: %v", err)), nil
 	}
+	commitDuration := time.Since(commitStart)
 
-	// プッシュされたファイルのパスを収集
+	// プッシュされたファイルのパスとアクションを収集
 	var pushedFiles []string
+	var pushedActions []map[string]interface{}
 	for _, a := range actions {
 		pushedFiles = append(pushedFiles, *a.FilePath)
+
+		pushed := map[string]interface{}{
+			"action":    *a.Action,
+			"file_path": *a.FilePath,
+		}
+		if a.PreviousPath != nil {
+			pushed["previous_path"] = *a.PreviousPath
+		}
+		pushedActions = append(pushedActions, pushed)
 	}
 
 	result := map[string]interface{}{
-		"commit_id":     commit.ID,
-		"commit_sha":    commit.ShortID,
-		"message":       commit.Message,
-		"branch":        branch,
-		"files_pushed":  pushedFiles,
-		"files_count":   len(pushedFiles),
-		"web_url":       commit.WebURL,
+		"commit_id":      commit.ID,
+		"commit_sha":     commit.ShortID,
+		"message":        commit.Message,
+		"branch":         branch,
+		"branch_created": branchCreated,
+		"forced":         force,
+		"files_pushed":   pushedFiles,
+		"actions":        pushedActions,
+		"files_count":    len(pushedFiles),
+		"web_url":        commit.WebURL,
+		"timing": map[string]interface{}{
+			"classify_ms":       classifyDuration.Milliseconds(),
+			"classify_requests": classifyRequests,
+			"commit_ms":         commitDuration.Milliseconds(),
+			"total_ms":          time.Since(start).Milliseconds(),
+		},
 	}
 	return jsonResult(result)
 }
 
+// validateCIConfigInActions はコミット対象に CI 設定ファイルが含まれる場合に lint を実行し、
+// 無効であればエラー結果を返す
+func validateCIConfigInActions(projectID, branch string, actions []*gitlab.CommitActionOptions) *mcp.CallToolResult {
+	project, _, err := gitlabClient.Projects.GetProject(projectID, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get project: %v", err))
+	}
+
+	ciConfigPath := project.CIConfigPath
+	if ciConfigPath == "" {
+		ciConfigPath = ".gitlab-ci.yml"
+	}
+
+	for _, a := range actions {
+		if *a.FilePath != ciConfigPath || a.Content == nil {
+			continue
+		}
+
+		content := *a.Content
+		if a.Encoding != nil && *a.Encoding == "base64" {
+			decoded, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to decode CI config content: %v", err))
+			}
+			content = string(decoded)
+		}
+
+		lint, err := lintCIContent(projectID, content, branch, false)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to lint CI config: %v", err))
+		}
+		if !lint.Valid {
+			jsonBytes, _ := json.MarshalIndent(map[string]interface{}{
+				"error":    fmt.Sprintf("%s is invalid; nothing was committed", ciConfigPath),
+				"errors":   lint.Errors,
+				"warnings": lint.Warnings,
+			}, "", "  ")
+			return mcp.NewToolResultError(string(jsonBytes))
+		}
+	}
+
+	return nil
+}
+
+// fileConflictResult はファイルが読み取り後に変更されていた場合の構造化エラーを返す
+func fileConflictResult(filePath, branch, expectedLastCommitID, expectedBlobSHA string, current *gitlab.File) *mcp.CallToolResult {
+	conflict := map[string]interface{}{
+		"error":                  "conflict",
+		"message":                "The file has changed since it was read; re-read it and retry",
+		"file_path":              filePath,
+		"branch":                 branch,
+		"current_last_commit_id": current.LastCommitID,
+		"current_blob_sha":       current.BlobID,
+		"current_content_sha256": current.SHA256,
+	}
+	if expectedLastCommitID != "" {
+		conflict["expected_last_commit_id"] = expectedLastCommitID
+	}
+	if expectedBlobSHA != "" {
+		conflict["expected_blob_sha"] = expectedBlobSHA
+	}
+
+	jsonBytes, _ := json.MarshalIndent(conflict, "", "  ")
+	return mcp.NewToolResultError(string(jsonBytes))
+}
+
+// isFileChangedError は last_commit_id の不一致による GitLab のエラーかどうかを判定する
+func isFileChangedError(err error) bool {
+	var errResp *gitlab.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	return errResp.Response.StatusCode == http.StatusBadRequest &&
+		strings.Contains(errResp.Message, "changed since")
+}
+
+// isNotFoundError は API が 404 を返したかを判定する
+func isNotFoundError(err error) bool {
+	return errors.Is(err, gitlab.ErrNotFound)
+}
+
 // ヘルパー関数
 
 func jsonResult(data interface{}) (*mcp.CallToolResult, error) {
@@ -948,6 +1345,13 @@ func getString(args map[string]interface{}, key, defaultVal string) string {
 	return defaultVal
 }
 
+func getBool(args map[string]interface{}, key string, defaultVal bool) bool {
+	if v, ok := args[key].(bool); ok {
+		return v
+	}
+	return defaultVal
+}
+
 func getInt(args map[string]interface{}, key string, defaultVal int) int {
 	if v, ok := args[key].(float64); ok {
 		return int(v)
@@ -1004,3 +1408,26 @@ func trimSpace(s string) string {
 	}
 	return s[start:end]
 }
+
+// isBinary は NUL バイトを含むデータをバイナリとみなす
+func isBinary(data []byte) bool {
+	for _, b := range data {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateString は maxChars を超える文字列を切り詰め、末尾に目印を付ける
+func truncateString(s string, maxChars int) string {
+	if maxChars <= 0 || len(s) <= maxChars {
+		return s
+	}
+	// マルチバイト文字の途中で切らないようにする
+	cut := maxChars
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "\n... (truncated)"
+}
@@ -0,0 +1,760 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// イテレーションの状態 (API の数値表現) の名前
+var iterationStates = map[int]string{
+	1: "upcoming",
+	2: "current",
+	3: "closed",
+}
+
+// iterationCadencesQuery はグループのイテレーションケイデンスを取得する GraphQL クエリ。
+// ケイデンスは REST API では取得できない。
+const iterationCadencesQuery = `query($fullPath: ID!) {
+  group(fullPath: $fullPath) {
+    iterationCadences(first: 100) {
+      nodes {
+        id
+        title
+        description
+        automatic
+        active
+        startDate
+        durationInWeeks
+        iterationsInAdvance
+        rollOver
+      }
+    }
+  }
+}`
+
+func registerMilestoneTools(s *server.MCPServer) {
+	// マイルストーン一覧取得
+	s.AddTool(
+		mcp.NewTool("list_milestones",
+			mcp.WithDescription("List milestones of a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("state",
+				mcp.Description("Filter by state: active, closed (default: all)"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Search milestones by title or description"),
+			),
+			mcp.WithBoolean("include_parent_milestones",
+				mcp.Description("Include milestones of parent groups (default: false)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of milestones per page (default: 20)"),
+			),
+		),
+		handleListMilestones,
+	)
+
+	// マイルストーン詳細取得
+	s.AddTool(
+		mcp.NewTool("get_milestone",
+			mcp.WithDescription("Get a project or group milestone"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithNumber("milestone_id",
+				mcp.Required(),
+				mcp.Description("Milestone ID (not IID)"),
+			),
+		),
+		handleGetMilestone,
+	)
+
+	// マイルストーン作成
+	s.AddTool(
+		mcp.NewTool("create_milestone",
+			mcp.WithDescription("Create a project or group milestone"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("title",
+				mcp.Required(),
+				mcp.Description("Milestone title"),
+			),
+			mcp.WithString("description",
+				mcp.Description("Milestone description"),
+			),
+			mcp.WithString("start_date",
+				mcp.Description("Start date (YYYY-MM-DD)"),
+			),
+			mcp.WithString("due_date",
+				mcp.Description("Due date (YYYY-MM-DD)"),
+			),
+		),
+		handleCreateMilestone,
+	)
+
+	// マイルストーン更新
+	s.AddTool(
+		mcp.NewTool("update_milestone",
+			mcp.WithDescription("Update a project or group milestone"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithNumber("milestone_id",
+				mcp.Required(),
+				mcp.Description("Milestone ID (not IID)"),
+			),
+			mcp.WithString("title",
+				mcp.Description("New title"),
+			),
+			mcp.WithString("description",
+				mcp.Description("New description"),
+			),
+			mcp.WithString("start_date",
+				mcp.Description("New start date (YYYY-MM-DD)"),
+			),
+			mcp.WithString("due_date",
+				mcp.Description("New due date (YYYY-MM-DD)"),
+			),
+			mcp.WithString("state_event",
+				mcp.Description("State change: close or activate"),
+			),
+		),
+		handleUpdateMilestone,
+	)
+
+	// マイルストーンのクローズ
+	s.AddTool(
+		mcp.NewTool("close_milestone",
+			mcp.WithDescription("Close a project or group milestone"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithNumber("milestone_id",
+				mcp.Required(),
+				mcp.Description("Milestone ID (not IID)"),
+			),
+		),
+		handleCloseMilestone,
+	)
+
+	// マイルストーンのイシュー・MR 一覧と進捗
+	s.AddTool(
+		mcp.NewTool("list_milestone_items",
+			mcp.WithDescription("List issues and merge requests in a milestone with burndown-style counts (open/closed issues, weights, merged MRs)"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithNumber("milestone_id",
+				mcp.Required(),
+				mcp.Description("Milestone ID (not IID)"),
+			),
+			mcp.WithString("type",
+				mcp.Description("Items to list: issues, merge_requests, all (default: all)"),
+			),
+			mcp.WithNumber("max_items",
+				mcp.Description("Maximum number of issues and of merge requests to return; counts always cover all items (default: 100)"),
+			),
+		),
+		handleListMilestoneItems,
+	)
+
+	// イテレーション一覧取得
+	s.AddTool(
+		mcp.NewTool("list_iterations",
+			mcp.WithDescription("List iterations of a group"),
+			mcp.WithString("group_id",
+				mcp.Required(),
+				mcp.Description("Group ID or path"),
+			),
+			mcp.WithString("state",
+				mcp.Description("Filter by state: opened, upcoming, current, closed, all (default: all)"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Search iterations by title"),
+			),
+			mcp.WithBoolean("include_ancestors",
+				mcp.Description("Include iterations of parent groups (default: true)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of iterations per page (default: 20)"),
+			),
+		),
+		handleListIterations,
+	)
+
+	// イテレーションケイデンス一覧取得
+	s.AddTool(
+		mcp.NewTool("list_iteration_cadences",
+			mcp.WithDescription("List iteration cadences of a group"),
+			mcp.WithString("group_id",
+				mcp.Required(),
+				mcp.Description("Group ID or path"),
+			),
+		),
+		handleListIterationCadences,
+	)
+}
+
+func handleListMilestones(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	listOpts := gitlab.ListOptions{
+		PerPage: getInt(args, "per_page", 20),
+	}
+	state := getString(args, "state", "")
+	search := getString(args, "search", "")
+	includeParent, hasIncludeParent := args["include_parent_milestones"].(bool)
+
+	var result []map[string]interface{}
+	if projectID != "" {
+		opts := &gitlab.ListMilestonesOptions{ListOptions: listOpts}
+		if state != "" {
+			opts.State = gitlab.Ptr(state)
+		}
+		if search != "" {
+			opts.Search = gitlab.Ptr(search)
+		}
+		if hasIncludeParent {
+			opts.IncludeParentMilestones = gitlab.Ptr(includeParent)
+		}
+		milestones, _, err := gitlabClient.Milestones.ListMilestones(projectID, opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list milestones: %v", err)), nil
+		}
+		for _, m := range milestones {
+			result = append(result, milestoneSummary(m))
+		}
+	} else {
+		opts := &gitlab.ListGroupMilestonesOptions{ListOptions: listOpts}
+		if state != "" {
+			opts.State = gitlab.Ptr(state)
+		}
+		if search != "" {
+			opts.Search = gitlab.Ptr(search)
+		}
+		if hasIncludeParent {
+			opts.IncludeParentMilestones = gitlab.Ptr(includeParent)
+		}
+		milestones, _, err := gitlabClient.GroupMilestones.ListGroupMilestones(groupID, opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list milestones: %v", err)), nil
+		}
+		for _, m := range milestones {
+			result = append(result, milestoneSummary(milestoneFromGroup(m)))
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleGetMilestone(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	milestoneID := getInt(args, "milestone_id", 0)
+	if milestoneID <= 0 {
+		return mcp.NewToolResultError("milestone_id is required"), nil
+	}
+
+	var milestone *gitlab.Milestone
+	if projectID != "" {
+		m, _, err := gitlabClient.Milestones.GetMilestone(projectID, milestoneID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get milestone: %v", err)), nil
+		}
+		milestone = m
+	} else {
+		m, _, err := gitlabClient.GroupMilestones.GetGroupMilestone(groupID, milestoneID)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get milestone: %v", err)), nil
+		}
+		milestone = milestoneFromGroup(m)
+	}
+
+	result := milestoneSummary(milestone)
+	result["description"] = milestone.Description
+	return jsonResult(result)
+}
+
+func handleCreateMilestone(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	title, ok := args["title"].(string)
+	if !ok || title == "" {
+		return mcp.NewToolResultError("title is required"), nil
+	}
+
+	startDate, err := parseDateArg(args, "start_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	dueDate, err := parseDateArg(args, "due_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var description *string
+	if desc := getString(args, "description", ""); desc != "" {
+		description = gitlab.Ptr(desc)
+	}
+
+	var milestone *gitlab.Milestone
+	if projectID != "" {
+		m, _, err := gitlabClient.Milestones.CreateMilestone(projectID, &gitlab.CreateMilestoneOptions{
+			Title:       gitlab.Ptr(title),
+			Description: description,
+			StartDate:   startDate,
+			DueDate:     dueDate,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create milestone: %v", err)), nil
+		}
+		milestone = m
+	} else {
+		m, _, err := gitlabClient.GroupMilestones.CreateGroupMilestone(groupID, &gitlab.CreateGroupMilestoneOptions{
+			Title:       gitlab.Ptr(title),
+			Description: description,
+			StartDate:   startDate,
+			DueDate:     dueDate,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create milestone: %v", err)), nil
+		}
+		milestone = milestoneFromGroup(m)
+	}
+
+	return jsonResult(milestoneSummary(milestone))
+}
+
+func handleUpdateMilestone(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	stateEvent := getString(args, "state_event", "")
+	if stateEvent != "" && stateEvent != "close" && stateEvent != "activate" {
+		return mcp.NewToolResultError("state_event must be close or activate"), nil
+	}
+	return updateMilestone(args, stateEvent)
+}
+
+func handleCloseMilestone(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return updateMilestone(req.Params.Arguments, "close")
+}
+
+// updateMilestone はプロジェクトまたはグループのマイルストーンを更新する
+func updateMilestone(args map[string]interface{}, stateEvent string) (*mcp.CallToolResult, error) {
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	milestoneID := getInt(args, "milestone_id", 0)
+	if milestoneID <= 0 {
+		return mcp.NewToolResultError("milestone_id is required"), nil
+	}
+
+	startDate, err := parseDateArg(args, "start_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	dueDate, err := parseDateArg(args, "due_date")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var title, description, state *string
+	if v := getString(args, "title", ""); v != "" {
+		title = gitlab.Ptr(v)
+	}
+	if v, ok := args["description"].(string); ok {
+		description = gitlab.Ptr(v)
+	}
+	if stateEvent != "" {
+		state = gitlab.Ptr(stateEvent)
+	}
+
+	var milestone *gitlab.Milestone
+	if projectID != "" {
+		m, _, err := gitlabClient.Milestones.UpdateMilestone(projectID, milestoneID, &gitlab.UpdateMilestoneOptions{
+			Title:       title,
+			Description: description,
+			StartDate:   startDate,
+			DueDate:     dueDate,
+			StateEvent:  state,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to update milestone: %v", err)), nil
+		}
+		milestone = m
+	} else {
+		m, _, err := gitlabClient.GroupMilestones.UpdateGroupMilestone(groupID, milestoneID, &gitlab.UpdateGroupMilestoneOptions{
+			Title:       title,
+			Description: description,
+			StartDate:   startDate,
+			DueDate:     dueDate,
+			StateEvent:  state,
+		})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to update milestone: %v", err)), nil
+		}
+		milestone = milestoneFromGroup(m)
+	}
+
+	return jsonResult(milestoneSummary(milestone))
+}
+
+func handleListMilestoneItems(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	milestoneID := getInt(args, "milestone_id", 0)
+	if milestoneID <= 0 {
+		return mcp.NewToolResultError("milestone_id is required"), nil
+	}
+
+	itemType := getString(args, "type", "all")
+	if itemType != "issues" && itemType != "merge_requests" && itemType != "all" {
+		return mcp.NewToolResultError("type must be issues, merge_requests or all"), nil
+	}
+	maxItems := getInt(args, "max_items", 100)
+	if maxItems < 1 {
+		return mcp.NewToolResultError("max_items must be at least 1"), nil
+	}
+
+	result := map[string]interface{}{
+		"milestone_id": milestoneID,
+	}
+
+	// 件数を正確に出すため全ページを取得し、返す項目だけを max_items で絞る
+	if itemType != "merge_requests" {
+		var issues []*gitlab.Issue
+		listOpts := gitlab.ListOptions{PerPage: 100, Page: 1}
+		for {
+			var page []*gitlab.Issue
+			var resp *gitlab.Response
+			var err error
+			if projectID != "" {
+				opts := gitlab.GetMilestoneIssuesOptions(listOpts)
+				page, resp, err = gitlabClient.Milestones.GetMilestoneIssues(projectID, milestoneID, &opts)
+			} else {
+				opts := gitlab.GetGroupMilestoneIssuesOptions(listOpts)
+				page, resp, err = gitlabClient.GroupMilestones.GetGroupMilestoneIssues(groupID, milestoneID, &opts)
+			}
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to list milestone issues: %v", err)), nil
+			}
+			issues = append(issues, page...)
+
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+
+		opened, closed, totalWeight, closedWeight := 0, 0, 0, 0
+		items := make([]map[string]interface{}, 0, min(len(issues), maxItems))
+		for _, issue := range issues {
+			totalWeight += issue.Weight
+			if issue.State == "closed" {
+				closed++
+				closedWeight += issue.Weight
+			} else {
+				opened++
+			}
+
+			if len(items) >= maxItems {
+				continue
+			}
+			assignees := make([]string, len(issue.Assignees))
+			for i, a := range issue.Assignees {
+				assignees[i] = a.Username
+			}
+			items = append(items, map[string]interface{}{
+				"iid":        issue.IID,
+				"title":      issue.Title,
+				"state":      issue.State,
+				"weight":     issue.Weight,
+				"assignees":  assignees,
+				"labels":     issue.Labels,
+				"project_id": issue.ProjectID,
+				"web_url":    issue.WebURL,
+			})
+		}
+
+		result["issues"] = items
+		result["issue_counts"] = map[string]interface{}{
+			"total":         len(issues),
+			"opened":        opened,
+			"closed":        closed,
+			"total_weight":  totalWeight,
+			"closed_weight": closedWeight,
+		}
+	}
+
+	if itemType != "issues" {
+		var mrs []*gitlab.MergeRequest
+		listOpts := gitlab.ListOptions{PerPage: 100, Page: 1}
+		for {
+			var page []*gitlab.MergeRequest
+			var resp *gitlab.Response
+			var err error
+			if projectID != "" {
+				opts := gitlab.GetMilestoneMergeRequestsOptions(listOpts)
+				page, resp, err = gitlabClient.Milestones.GetMilestoneMergeRequests(projectID, milestoneID, &opts)
+			} else {
+				opts := gitlab.GetGroupMilestoneMergeRequestsOptions(listOpts)
+				page, resp, err = gitlabClient.GroupMilestones.GetGroupMilestoneMergeRequests(groupID, milestoneID, &opts)
+			}
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to list milestone merge requests: %v", err)), nil
+			}
+			mrs = append(mrs, page...)
+
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+			listOpts.Page = resp.NextPage
+		}
+
+		counts := map[string]int{"opened": 0, "merged": 0, "closed": 0}
+		items := make([]map[string]interface{}, 0, min(len(mrs), maxItems))
+		for _, mr := range mrs {
+			counts[mr.State]++
+
+			if len(items) >= maxItems {
+				continue
+			}
+			item := map[string]interface{}{
+				"iid":        mr.IID,
+				"title":      mr.Title,
+				"state":      mr.State,
+				"labels":     mr.Labels,
+				"project_id": mr.ProjectID,
+				"web_url":    mr.WebURL,
+			}
+			if mr.Author != nil {
+				item["author"] = mr.Author.Username
+			}
+			items = append(items, item)
+		}
+
+		result["merge_requests"] = items
+		result["merge_request_counts"] = map[string]interface{}{
+			"total":  len(mrs),
+			"opened": counts["opened"],
+			"merged": counts["merged"],
+			"closed": counts["closed"],
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleListIterations(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	groupID, ok := args["group_id"].(string)
+	if !ok || groupID == "" {
+		return mcp.NewToolResultError("group_id is required"), nil
+	}
+
+	opts := &gitlab.ListGroupIterationsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: getInt(args, "per_page", 20),
+		},
+	}
+	if state := getString(args, "state", ""); state != "" {
+		opts.State = gitlab.Ptr(state)
+	}
+	if search := getString(args, "search", ""); search != "" {
+		opts.Search = gitlab.Ptr(search)
+	}
+	if includeAncestors, ok := args["include_ancestors"].(bool); ok {
+		opts.IncludeAncestors = gitlab.Ptr(includeAncestors)
+	}
+
+	iterations, _, err := gitlabClient.GroupIterations.ListGroupIterations(groupID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list iterations: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(iterations))
+	for i, it := range iterations {
+		result[i] = map[string]interface{}{
+			"id":         it.ID,
+			"iid":        it.IID,
+			"sequence":   it.Sequence,
+			"title":      it.Title,
+			"state":      iterationStates[it.State],
+			"start_date": it.StartDate,
+			"due_date":   it.DueDate,
+			"group_id":   it.GroupID,
+			"web_url":    it.WebURL,
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleListIterationCadences(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	groupID, ok := args["group_id"].(string)
+	if !ok || groupID == "" {
+		return mcp.NewToolResultError("group_id is required"), nil
+	}
+
+	// GraphQL はフルパスでグループを指定するため、ID の場合に備えて解決する
+	group, _, err := gitlabClient.Groups.GetGroup(groupID, &gitlab.GetGroupOptions{
+		WithProjects: gitlab.Ptr(false),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get group: %v", err)), nil
+	}
+
+	var data struct {
+		Group *struct {
+			IterationCadences struct {
+				Nodes []map[string]interface{} `json:"nodes"`
+			} `json:"iterationCadences"`
+		} `json:"group"`
+	}
+	if err := graphQLQuery(iterationCadencesQuery, map[string]interface{}{"fullPath": group.FullPath}, &data); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list iteration cadences: %v", err)), nil
+	}
+	if data.Group == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("group not found: %s", group.FullPath)), nil
+	}
+
+	return jsonResult(data.Group.IterationCadences.Nodes)
+}
+
+// graphQLQuery は GitLab の GraphQL API にクエリを送り、data を result にデコードする
+func graphQLQuery(query string, variables map[string]interface{}, result interface{}) error {
+	body := map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	}
+	httpReq, err := gitlabClient.NewRequest(http.MethodPost, "", body, nil)
+	if err != nil {
+		return err
+	}
+	// REST のベース URL (.../api/v4/) を GraphQL のエンドポイント (.../api/graphql) に置き換える
+	httpReq.URL.Path = strings.TrimSuffix(strings.TrimSuffix(httpReq.URL.Path, "/"), "v4") + "graphql"
+	httpReq.URL.RawPath = ""
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := gitlabClient.Do(httpReq, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		messages := make([]string, len(resp.Errors))
+		for i, e := range resp.Errors {
+			messages[i] = e.Message
+		}
+		return fmt.Errorf("%s", strings.Join(messages, "; "))
+	}
+	return json.Unmarshal(resp.Data, result)
+}
+
+// parseDateArg は YYYY-MM-DD 形式の日付引数を解釈する。指定がなければ nil を返す
+func parseDateArg(args map[string]interface{}, key string) (*gitlab.ISOTime, error) {
+	v := getString(args, key, "")
+	if v == "" {
+		return nil, nil
+	}
+	t, err := gitlab.ParseISOTime(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s (expected YYYY-MM-DD)", key, v)
+	}
+	return &t, nil
+}
+
+// milestoneFromGroup はグループマイルストーンをプロジェクトマイルストーンと同じ形に変換する
+func milestoneFromGroup(m *gitlab.GroupMilestone) *gitlab.Milestone {
+	return &gitlab.Milestone{
+		ID:          m.ID,
+		IID:         m.IID,
+		GroupID:     m.GroupID,
+		Title:       m.Title,
+		Description: m.Description,
+		StartDate:   m.StartDate,
+		DueDate:     m.DueDate,
+		State:       m.State,
+		UpdatedAt:   m.UpdatedAt,
+		CreatedAt:   m.CreatedAt,
+		Expired:     m.Expired,
+	}
+}
+
+func milestoneSummary(m *gitlab.Milestone) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":         m.ID,
+		"iid":        m.IID,
+		"title":      m.Title,
+		"state":      m.State,
+		"start_date": m.StartDate,
+		"due_date":   m.DueDate,
+	}
+	if m.ProjectID != 0 {
+		result["project_id"] = m.ProjectID
+	}
+	if m.GroupID != 0 {
+		result["group_id"] = m.GroupID
+	}
+	if m.Expired != nil {
+		result["expired"] = *m.Expired
+	}
+	if m.WebURL != "" {
+		result["web_url"] = m.WebURL
+	}
+	return result
+}
@@ -0,0 +1,1049 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// パッチ適用時のデフォルトの fuzz (無視できる前後のコンテキスト行数)
+const defaultPatchFuzz = 2
+
+// 差分計算で探索する編集距離の上限 (前方・後方それぞれ)
+const maxDiffSearchDepth = 2000
+
+// filePatch は 1 ファイル分の差分
+type filePatch struct {
+	OldPath  string
+	NewPath  string
+	IsNew    bool
+	IsDelete bool
+	IsRename bool
+	IsBinary bool
+	NewMode  string
+	Hunks    []*patchHunk
+}
+
+// patchHunk は 1 つのハンク
+type patchHunk struct {
+	Header   string
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []patchLine
+}
+
+// patchLine はハンク内の 1 行。NoEOL は直後に "\ No newline at end of file" が続くことを表す
+type patchLine struct {
+	Op    byte
+	Text  string
+	NoEOL bool
+}
+
+// fileLine は適用対象ファイルの 1 行
+type fileLine struct {
+	Text  string
+	NoEOL bool
+}
+
+// hunkReject は適用できなかったハンクの詳細
+type hunkReject struct {
+	File     string   `json:"file"`
+	Hunk     int      `json:"hunk"`
+	Header   string   `json:"header"`
+	Reason   string   `json:"reason"`
+	Expected []string `json:"expected,omitempty"`
+	Actual   []string `json:"actual,omitempty"`
+}
+
+func registerPatchTools(s *server.MCPServer) {
+	// パッチ適用
+	s.AddTool(
+		mcp.NewTool("apply_patch",
+			mcp.WithDescription("Apply a unified diff (multi-file, git-style with new/deleted/renamed files) to a branch and commit the result atomically"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Branch to apply the patch to"),
+			),
+			mcp.WithString("patch",
+				mcp.Required(),
+				mcp.Description("Unified diff, e.g. the output of 'git diff'"),
+			),
+			mcp.WithString("commit_message",
+				mcp.Required(),
+				mcp.Description("Commit message"),
+			),
+			mcp.WithNumber("fuzz",
+				mcp.Description("Maximum number of leading/trailing context lines that may be ignored when a hunk does not match exactly (default: 2)"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Only check whether the patch applies, without committing (default: false)"),
+			),
+			mcp.WithString("author_email",
+				mcp.Description("Author email for the commit"),
+			),
+			mcp.WithString("author_name",
+				mcp.Description("Author name for the commit"),
+			),
+		),
+		handleApplyPatch,
+	)
+
+	// 検索・置換によるファイル編集
+	s.AddTool(
+		mcp.NewTool("edit_file",
+			mcp.WithDescription("Edit a file on a branch by exact search-and-replace and commit the result. Returns a unified diff of the change"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("file_path",
+				mcp.Required(),
+				mcp.Description("Path to the file in the repository"),
+			),
+			mcp.WithString("branch",
+				mcp.Required(),
+				mcp.Description("Branch to edit"),
+			),
+			mcp.WithArray("edits",
+				mcp.Required(),
+				mcp.Description("Array of edits applied in order, each with 'old_string', 'new_string' and optional 'replace_all' (default: false). Without replace_all, old_string must match exactly once"),
+			),
+			mcp.WithString("commit_message",
+				mcp.Required(),
+				mcp.Description("Commit message"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Only return the diff, without committing (default: false)"),
+			),
+			mcp.WithString("author_email",
+				mcp.Description("Author email for the commit"),
+			),
+			mcp.WithString("author_name",
+				mcp.Description("Author name for the commit"),
+			),
+		),
+		handleEditFile,
+	)
+}
+
+func handleApplyPatch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	patchText, ok := args["patch"].(string)
+	if !ok || patchText == "" {
+		return mcp.NewToolResultError("patch is required"), nil
+	}
+
+	commitMessage, ok := args["commit_message"].(string)
+	if !ok || commitMessage == "" {
+		return mcp.NewToolResultError("commit_message is required"), nil
+	}
+
+	fuzz := getInt(args, "fuzz", defaultPatchFuzz)
+	dryRun := getBool(args, "dry_run", false)
+
+	patches, err := parseUnifiedDiff(patchText)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to parse patch: %v", err)), nil
+	}
+	if len(patches) == 0 {
+		return mcp.NewToolResultError("patch does not contain any file changes"), nil
+	}
+
+	var actions []*gitlab.CommitActionOptions
+	var rejects []hunkReject
+	var files []map[string]interface{}
+
+	for _, fp := range patches {
+		if fp.IsBinary {
+			rejects = append(rejects, hunkReject{File: fp.NewPath, Reason: "binary patches are not supported"})
+			continue
+		}
+
+		fileResult := map[string]interface{}{
+			"file_path": fp.NewPath,
+			"hunks":     len(fp.Hunks),
+		}
+
+		// 新規ファイルは追加行のみから内容を組み立てる
+		if fp.IsNew {
+			lines, offsets, fileRejects := applyHunks(fp.NewPath, nil, fp.Hunks, fuzz)
+			if len(fileRejects) > 0 {
+				rejects = append(rejects, fileRejects...)
+				continue
+			}
+			action := &gitlab.CommitActionOptions{
+				Action:   gitlab.Ptr(gitlab.FileCreate),
+				FilePath: gitlab.Ptr(fp.NewPath),
+				Content:  gitlab.Ptr(joinFileLines(lines)),
+			}
+			if fp.NewMode == "100755" {
+				action.ExecuteFilemode = gitlab.Ptr(true)
+			}
+			actions = append(actions, action)
+			fileResult["action"] = "create"
+			fileResult["offsets"] = offsets
+			files = append(files, fileResult)
+			continue
+		}
+
+		// 既存ファイルはブランチの先頭から取得する
+		file, _, err := gitlabClient.RepositoryFiles.GetFile(projectID, fp.OldPath, &gitlab.GetFileOptions{
+			Ref: gitlab.Ptr(branch),
+		})
+		if err != nil {
+			rejects = append(rejects, hunkReject{File: fp.OldPath, Reason: fmt.Sprintf("failed to get file: %v", err)})
+			continue
+		}
+
+		if fp.IsDelete {
+			actions = append(actions, &gitlab.CommitActionOptions{
+				Action:       gitlab.Ptr(gitlab.FileDelete),
+				FilePath:     gitlab.Ptr(fp.OldPath),
+				LastCommitID: gitlab.Ptr(file.LastCommitID),
+			})
+			fileResult["file_path"] = fp.OldPath
+			fileResult["action"] = "delete"
+			files = append(files, fileResult)
+			continue
+		}
+
+		// 内容の変更を伴わないモード変更
+		if !fp.IsRename && len(fp.Hunks) == 0 && fp.NewMode != "" {
+			actions = append(actions, &gitlab.CommitActionOptions{
+				Action:          gitlab.Ptr(gitlab.FileChmod),
+				FilePath:        gitlab.Ptr(fp.NewPath),
+				ExecuteFilemode: gitlab.Ptr(fp.NewMode == "100755"),
+			})
+			fileResult["action"] = "chmod"
+			files = append(files, fileResult)
+			continue
+		}
+
+		data, err := decodeFileContent(file)
+		if err != nil {
+			rejects = append(rejects, hunkReject{File: fp.OldPath, Reason: fmt.Sprintf("failed to decode file: %v", err)})
+			continue
+		}
+		if isBinary(data) {
+			rejects = append(rejects, hunkReject{File: fp.OldPath, Reason: "target file is binary"})
+			continue
+		}
+
+		lines, offsets, fileRejects := applyHunks(fp.OldPath, splitFileLines(string(data)), fp.Hunks, fuzz)
+		if len(fileRejects) > 0 {
+			rejects = append(rejects, fileRejects...)
+			continue
+		}
+
+		action := &gitlab.CommitActionOptions{
+			FilePath:     gitlab.Ptr(fp.NewPath),
+			Content:      gitlab.Ptr(joinFileLines(lines)),
+			LastCommitID: gitlab.Ptr(file.LastCommitID),
+		}
+		switch {
+		case fp.IsRename:
+			action.Action = gitlab.Ptr(gitlab.FileMove)
+			action.PreviousPath = gitlab.Ptr(fp.OldPath)
+			fileResult["action"] = "move"
+			fileResult["previous_path"] = fp.OldPath
+		default:
+			action.Action = gitlab.Ptr(gitlab.FileUpdate)
+			fileResult["action"] = "update"
+		}
+		if fp.NewMode != "" {
+			action.ExecuteFilemode = gitlab.Ptr(fp.NewMode == "100755")
+		}
+		actions = append(actions, action)
+		fileResult["offsets"] = offsets
+		files = append(files, fileResult)
+	}
+
+	// 1 つでも適用できないハンクがあればコミットしない
+	if len(rejects) > 0 {
+		jsonBytes, _ := json.MarshalIndent(map[string]interface{}{
+			"error":   "patch does not apply; nothing was committed",
+			"rejects": rejects,
+			"applied": files,
+		}, "", "  ")
+		return mcp.NewToolResultError(string(jsonBytes)), nil
+	}
+
+	if dryRun {
+		result := map[string]interface{}{
+			"dry_run": true,
+			"branch":  branch,
+			"files":   files,
+		}
+		return jsonResult(result)
+	}
+
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        gitlab.Ptr(branch),
+		CommitMessage: gitlab.Ptr(commitMessage),
+		Actions:       actions,
+	}
+
+	if authorEmail := getString(args, "author_email", ""); authorEmail != "" {
+		opts.AuthorEmail = gitlab.Ptr(authorEmail)
+	}
+	if authorName := getString(args, "author_name", ""); authorName != "" {
+		opts.AuthorName = gitlab.Ptr(authorName)
+	}
+
+	commit, _, err := gitlabClient.Commits.CreateCommit(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to commit patch: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"commit_id":  commit.ID,
+		"commit_sha": commit.ShortID,
+		"message":    commit.Message,
+		"branch":     branch,
+		"files":      files,
+		"web_url":    commit.WebURL,
+	}
+	return jsonResult(result)
+}
+
+func handleEditFile(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return mcp.NewToolResultError("file_path is required"), nil
+	}
+
+	branch, ok := args["branch"].(string)
+	if !ok || branch == "" {
+		return mcp.NewToolResultError("branch is required"), nil
+	}
+
+	editsArg, ok := args["edits"].([]interface{})
+	if !ok || len(editsArg) == 0 {
+		return mcp.NewToolResultError("edits is required and must be a non-empty array"), nil
+	}
+
+	commitMessage, ok := args["commit_message"].(string)
+	if !ok || commitMessage == "" {
+		return mcp.NewToolResultError("commit_message is required"), nil
+	}
+
+	file, _, err := gitlabClient.RepositoryFiles.GetFile(projectID, filePath, &gitlab.GetFileOptions{
+		Ref: gitlab.Ptr(branch),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get file: %v", err)), nil
+	}
+
+	data, err := decodeFileContent(file)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to decode file content: %v", err)), nil
+	}
+	if isBinary(data) {
+		return mcp.NewToolResultError("file is binary and cannot be edited"), nil
+	}
+
+	original := string(data)
+	content := original
+	replacements := 0
+	for i, e := range editsArg {
+		edit, ok := e.(map[string]interface{})
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d] must be an object", i)), nil
+		}
+
+		oldString, ok := edit["old_string"].(string)
+		if !ok || oldString == "" {
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d].old_string is required", i)), nil
+		}
+		newString, ok := edit["new_string"].(string)
+		if !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d].new_string is required", i)), nil
+		}
+		if oldString == newString {
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d]: old_string and new_string are identical", i)), nil
+		}
+
+		count := strings.Count(content, oldString)
+		switch {
+		case count == 0:
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d]: old_string not found in %s", i, filePath)), nil
+		case count > 1 && !getBool(edit, "replace_all", false):
+			return mcp.NewToolResultError(fmt.Sprintf("edits[%d]: old_string matches %d times in %s; add surrounding context to make it unique or set replace_all", i, count, filePath)), nil
+		}
+
+		if getBool(edit, "replace_all", false) {
+			content = strings.ReplaceAll(content, oldString, newString)
+		} else {
+			content = strings.Replace(content, oldString, newString, 1)
+		}
+		replacements += count
+	}
+
+	diff := unifiedDiff(filePath, original, content, 3)
+	if diff == "" {
+		return mcp.NewToolResultError("edits do not change the file"), nil
+	}
+
+	if getBool(args, "dry_run", false) {
+		result := map[string]interface{}{
+			"dry_run":      true,
+			"file_path":    filePath,
+			"branch":       branch,
+			"replacements": replacements,
+			"diff":         diff,
+		}
+		return jsonResult(result)
+	}
+
+	opts := &gitlab.UpdateFileOptions{
+		Branch:        gitlab.Ptr(branch),
+		Content:       gitlab.Ptr(content),
+		CommitMessage: gitlab.Ptr(commitMessage),
+		LastCommitID:  gitlab.Ptr(file.LastCommitID),
+	}
+	if authorEmail := getString(args, "author_email", ""); authorEmail != "" {
+		opts.AuthorEmail = gitlab.Ptr(authorEmail)
+	}
+	if authorName := getString(args, "author_name", ""); authorName != "" {
+		opts.AuthorName = gitlab.Ptr(authorName)
+	}
+
+	fileInfo, _, err := gitlabClient.RepositoryFiles.UpdateFile(projectID, filePath, opts)
+	if err != nil {
+		if isFileChangedError(err) {
+			current, _, metaErr := gitlabClient.RepositoryFiles.GetFileMetaData(projectID, filePath, &gitlab.GetFileMetaDataOptions{
+				Ref: gitlab.Ptr(branch),
+			})
+			if metaErr == nil {
+				return fileConflictResult(filePath, branch, file.LastCommitID, "", current), nil
+			}
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to commit edit: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"file_path":    fileInfo.FilePath,
+		"branch":       fileInfo.Branch,
+		"replacements": replacements,
+		"diff":         diff,
+	}
+	return jsonResult(result)
+}
+
+// parseUnifiedDiff は git 形式および素の unified diff をファイル単位に分解する
+func parseUnifiedDiff(text string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var patches []*filePatch
+	var cur *filePatch
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = &filePatch{}
+			patches = append(patches, cur)
+			if oldPath, newPath, ok := parseGitDiffHeader(line); ok {
+				cur.OldPath, cur.NewPath = oldPath, newPath
+			}
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			// git ヘッダーのない素の unified diff
+			if cur == nil || len(cur.Hunks) > 0 {
+				cur = &filePatch{}
+				patches = append(patches, cur)
+			}
+			oldPath := parsePatchPath(line[4:])
+			newPath := parsePatchPath(lines[i+1][4:])
+			if oldPath == "/dev/null" {
+				cur.IsNew = true
+			} else {
+				cur.OldPath = oldPath
+			}
+			if newPath == "/dev/null" {
+				cur.IsDelete = true
+			} else {
+				cur.NewPath = newPath
+			}
+			i++
+
+		case cur == nil:
+			// 先頭のコメントなどは読み飛ばす
+
+		case strings.HasPrefix(line, "new file mode "):
+			cur.IsNew = true
+			cur.NewMode = strings.TrimPrefix(line, "new file mode ")
+
+		case strings.HasPrefix(line, "deleted file mode "):
+			cur.IsDelete = true
+
+		case strings.HasPrefix(line, "new mode "):
+			cur.NewMode = strings.TrimPrefix(line, "new mode ")
+
+		case strings.HasPrefix(line, "rename from "):
+			cur.IsRename = true
+			cur.OldPath = strings.TrimPrefix(line, "rename from ")
+
+		case strings.HasPrefix(line, "rename to "):
+			cur.IsRename = true
+			cur.NewPath = strings.TrimPrefix(line, "rename to ")
+
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			cur.IsBinary = true
+
+		case strings.HasPrefix(line, "@@ "):
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+
+			// ヘッダーの行数に従ってハンク本体を読む
+			oldCount, newCount := 0, 0
+			for i+1 < len(lines) && (oldCount < h.OldLines || newCount < h.NewLines) {
+				body := lines[i+1]
+				if body == "" {
+					// 末尾の空白が削られた空のコンテキスト行
+					body = " "
+				}
+				op := body[0]
+				switch op {
+				case ' ':
+					oldCount++
+					newCount++
+				case '-':
+					oldCount++
+				case '+':
+					newCount++
+				case '\\':
+					if len(h.Lines) > 0 {
+						h.Lines[len(h.Lines)-1].NoEOL = true
+					}
+					i++
+					continue
+				default:
+					return nil, fmt.Errorf("unexpected line in hunk %q: %q", h.Header, body)
+				}
+				h.Lines = append(h.Lines, patchLine{Op: op, Text: body[1:]})
+				i++
+			}
+			if oldCount != h.OldLines || newCount != h.NewLines {
+				return nil, fmt.Errorf("hunk %q is truncated", h.Header)
+			}
+
+			// ハンク直後の "\ No newline at end of file"
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\\") && len(h.Lines) > 0 {
+				h.Lines[len(h.Lines)-1].NoEOL = true
+				i++
+			}
+			cur.Hunks = append(cur.Hunks, h)
+		}
+	}
+
+	for _, p := range patches {
+		if p.IsNew {
+			p.OldPath = ""
+		}
+		if p.IsDelete && p.NewPath == "" {
+			p.NewPath = p.OldPath
+		}
+		if !p.IsNew && p.OldPath == "" {
+			p.OldPath = p.NewPath
+		}
+		if p.NewPath == "" {
+			return nil, fmt.Errorf("could not determine the file path of a diff section")
+		}
+	}
+
+	return patches, nil
+}
+
+// parseGitDiffHeader は "diff --git a/x b/y" からパスを取り出す
+func parseGitDiffHeader(line string) (string, string, bool) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	idx := strings.Index(rest, " b/")
+	if !strings.HasPrefix(rest, "a/") || idx < 0 {
+		return "", "", false
+	}
+	return rest[2:idx], rest[idx+3:], true
+}
+
+// parsePatchPath は --- / +++ 行のパスからタイムスタンプと a/ b/ 接頭辞を取り除く
+func parsePatchPath(s string) string {
+	if idx := strings.IndexByte(s, '\t'); idx >= 0 {
+		s = s[:idx]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return s
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		return s[2:]
+	}
+	return s
+}
+
+// parseHunkHeader は "@@ -l,s +l,s @@" を解析する
+func parseHunkHeader(line string) (*patchHunk, error) {
+	h := &patchHunk{Header: line}
+
+	end := strings.Index(line[3:], " @@")
+	if end < 0 {
+		return nil, fmt.Errorf("invalid hunk header: %q", line)
+	}
+	ranges := strings.Fields(line[3 : 3+end])
+	if len(ranges) != 2 || !strings.HasPrefix(ranges[0], "-") || !strings.HasPrefix(ranges[1], "+") {
+		return nil, fmt.Errorf("invalid hunk header: %q", line)
+	}
+
+	var err error
+	if h.OldStart, h.OldLines, err = parseHunkRange(ranges[0][1:]); err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %v", line, err)
+	}
+	if h.NewStart, h.NewLines, err = parseHunkRange(ranges[1][1:]); err != nil {
+		return nil, fmt.Errorf("invalid hunk header %q: %v", line, err)
+	}
+	return h, nil
+}
+
+func parseHunkRange(s string) (int, int, error) {
+	start, count := s, "1"
+	if idx := strings.IndexByte(s, ','); idx >= 0 {
+		start, count = s[:idx], s[idx+1:]
+	}
+	a, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.Atoi(count)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+// applyHunks はハンクを順に適用する。各ハンクについて期待位置からのずれ (offset) を返す
+func applyHunks(filePath string, lines []fileLine, hunks []*patchHunk, fuzz int) ([]fileLine, []int, []hunkReject) {
+	var rejects []hunkReject
+	offsets := make([]int, 0, len(hunks))
+
+	delta := 0  // これまでのハンクによる行数の増減
+	minPos := 0 // 次のハンクが適用できる最小位置
+	for n, h := range hunks {
+		var oldSide, newSide []patchLine
+		for _, l := range h.Lines {
+			if l.Op != '+' {
+				oldSide = append(oldSide, l)
+			}
+			if l.Op != '-' {
+				newSide = append(newSide, l)
+			}
+		}
+
+		expected := h.OldStart - 1 + delta
+		if h.OldLines == 0 {
+			// 追加のみのハンクは OldStart 行の直後に挿入する
+			expected = h.OldStart + delta
+		}
+
+		pos, trimHead, trimTail, found := locateHunk(lines, oldSide, expected, minPos, fuzz)
+		if !found {
+			reject := hunkReject{
+				File:   filePath,
+				Hunk:   n + 1,
+				Header: h.Header,
+				Reason: fmt.Sprintf("context does not match near line %d", expected+1),
+			}
+			for _, l := range oldSide {
+				reject.Expected = append(reject.Expected, l.Text)
+			}
+			for i := expected; i >= 0 && i < len(lines) && i < expected+len(oldSide); i++ {
+				reject.Actual = append(reject.Actual, lines[i].Text)
+			}
+			rejects = append(rejects, reject)
+			continue
+		}
+
+		// fuzz で無視したコンテキスト行は新しい側からも外す
+		matchedOld := len(oldSide) - trimHead - trimTail
+		replacement := make([]fileLine, 0, len(newSide))
+		for _, l := range newSide[trimHead : len(newSide)-trimTail] {
+			replacement = append(replacement, fileLine{Text: l.Text, NoEOL: l.NoEOL})
+		}
+
+		updated := make([]fileLine, 0, len(lines)-matchedOld+len(replacement))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, replacement...)
+		updated = append(updated, lines[pos+matchedOld:]...)
+		lines = updated
+
+		offsets = append(offsets, pos-trimHead-expected)
+		delta += len(replacement) - matchedOld
+		minPos = pos + len(replacement)
+	}
+
+	return lines, offsets, rejects
+}
+
+// locateHunk はハンクの旧側の行が一致する位置を期待位置の近くから探す。
+// 完全一致、行末空白を無視した一致、fuzz によるコンテキスト削減の順に試す
+func locateHunk(lines []fileLine, oldSide []patchLine, expected, minPos, fuzz int) (int, int, int, bool) {
+	leadingContext, trailingContext := 0, 0
+	for _, l := range oldSide {
+		if l.Op != ' ' {
+			break
+		}
+		leadingContext++
+	}
+	for i := len(oldSide) - 1; i >= 0 && oldSide[i].Op == ' '; i-- {
+		trailingContext++
+	}
+
+	for f := 0; f <= fuzz; f++ {
+		trimHead, trimTail := min(f, leadingContext), min(f, trailingContext)
+		if f > 0 && trimHead+trimTail == 0 {
+			break
+		}
+		if trimHead+trimTail >= len(oldSide) && len(oldSide) > 0 {
+			break
+		}
+		candidate := oldSide[trimHead : len(oldSide)-trimTail]
+
+		for _, exact := range []bool{true, false} {
+			if pos, ok := searchLines(lines, candidate, expected+trimHead, minPos, exact); ok {
+				return pos, trimHead, trimTail, true
+			}
+		}
+	}
+	return 0, 0, 0, false
+}
+
+// searchLines は期待位置から前後に広げながら一致する位置を探す
+func searchLines(lines []fileLine, want []patchLine, expected, minPos int, exact bool) (int, bool) {
+	maxPos := len(lines) - len(want)
+	if maxPos < minPos {
+		return 0, false
+	}
+	if expected < minPos {
+		expected = minPos
+	}
+	if expected > maxPos {
+		expected = maxPos
+	}
+
+	for dist := 0; ; dist++ {
+		before, after := expected-dist, expected+dist
+		if before < minPos && after > maxPos {
+			return 0, false
+		}
+		if after <= maxPos && linesMatch(lines[after:], want, exact) {
+			return after, true
+		}
+		if dist > 0 && before >= minPos && before <= maxPos && linesMatch(lines[before:], want, exact) {
+			return before, true
+		}
+	}
+}
+
+func linesMatch(lines []fileLine, want []patchLine, exact bool) bool {
+	for i, w := range want {
+		got := lines[i].Text
+		if exact {
+			if got != w.Text {
+				return false
+			}
+		} else if strings.TrimRight(got, " \t") != strings.TrimRight(w.Text, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// splitFileLines はファイル内容を行に分割し、末尾の改行の有無を保持する
+func splitFileLines(content string) []fileLine {
+	if content == "" {
+		return nil
+	}
+	parts := strings.Split(content, "\n")
+	noEOL := true
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+		noEOL = false
+	}
+
+	lines := make([]fileLine, len(parts))
+	for i, p := range parts {
+		lines[i] = fileLine{Text: p}
+	}
+	lines[len(lines)-1].NoEOL = noEOL
+	return lines
+}
+
+// joinFileLines は行を結合してファイル内容に戻す
+func joinFileLines(lines []fileLine) string {
+	var b strings.Builder
+	for i, l := range lines {
+		b.WriteString(l.Text)
+		if i < len(lines)-1 || !l.NoEOL {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// diffOp は差分の 1 行。op は ' ' (共通)、'-' (削除)、'+' (追加) のいずれか
+type diffOp struct {
+	op   byte
+	line fileLine
+}
+
+// unifiedDiff は 2 つの内容の差分を unified diff 形式で返す。差分がなければ空文字列を返す
+func unifiedDiff(filePath, oldContent, newContent string, context int) string {
+	a := splitFileLines(oldContent)
+	b := splitFileLines(newContent)
+
+	ops := diffLines(a, b)
+
+	// 変更箇所の前後 context 行をまとめてハンクにする
+	var b2 strings.Builder
+	fmt.Fprintf(&b2, "--- a/%s\n+++ b/%s\n", filePath, filePath)
+	hasChanges := false
+
+	oldLine, newLine := 0, 0 // ops[k] より前に消費した行数
+	for k := 0; k < len(ops); {
+		if ops[k].op == ' ' {
+			oldLine++
+			newLine++
+			k++
+			continue
+		}
+
+		start := max(k-context, 0)
+		end := k
+		for end < len(ops) {
+			if ops[end].op != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].op == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+
+		oldStart, newStart := oldLine-(k-start), newLine-(k-start)
+		oldCount, newCount := 0, 0
+		var body strings.Builder
+		for _, o := range ops[start:end] {
+			body.WriteByte(o.op)
+			body.WriteString(o.line.Text)
+			body.WriteString("\n")
+			if o.line.NoEOL {
+				body.WriteString("\\ No newline at end of file\n")
+			}
+			if o.op != '+' {
+				oldCount++
+			}
+			if o.op != '-' {
+				newCount++
+			}
+		}
+
+		if oldCount > 0 {
+			oldStart++
+		}
+		if newCount > 0 {
+			newStart++
+		}
+		fmt.Fprintf(&b2, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		b2.WriteString(body.String())
+		hasChanges = true
+
+		for _, o := range ops[k:end] {
+			if o.op != '+' {
+				oldLine++
+			}
+			if o.op != '-' {
+				newLine++
+			}
+		}
+		k = end
+	}
+
+	if !hasChanges {
+		return ""
+	}
+	return b2.String()
+}
+
+// diffLines は Myers の線形空間アルゴリズムで 2 つの行列の差分を求める。
+// 変更が連続する箇所では削除行を追加行より先に並べる
+func diffLines(a, b []fileLine) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	ops = appendDiff(ops, a, b)
+
+	for k := 0; k < len(ops); {
+		if ops[k].op == ' ' {
+			k++
+			continue
+		}
+		end := k
+		for end < len(ops) && ops[end].op != ' ' {
+			end++
+		}
+		sort.SliceStable(ops[k:end], func(i, j int) bool {
+			return ops[k+i].op == '-' && ops[k+j].op == '+'
+		})
+		k = end
+	}
+	return ops
+}
+
+// appendDiff は共通の先頭・末尾を除き、中央のスネークで分割して再帰的に差分を求める
+func appendDiff(ops []diffOp, a, b []fileLine) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	x, y, ok := middleSnake(a, b)
+	switch {
+	case len(a) == 0 || len(b) == 0 || !ok:
+		for _, l := range a {
+			ops = append(ops, diffOp{'-', l})
+		}
+		for _, l := range b {
+			ops = append(ops, diffOp{'+', l})
+		}
+	default:
+		ops = appendDiff(ops, a[:x], b[:y])
+		ops = appendDiff(ops, a[x:], b[y:])
+	}
+
+	for _, l := range common {
+		ops = append(ops, diffOp{' ', l})
+	}
+	return ops
+}
+
+// middleSnake は前方と後方から同時に探索し、最短編集経路上の分割点を返す。
+// 分割しても小さくならない場合や探索が上限に達した場合は false を返す
+func middleSnake(a, b []fileLine) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	// 編集距離が大きすぎる場合は探索を打ち切り、範囲全体を置き換える
+	maxD := min((n+m+1)/2, maxDiffSearchDepth)
+	offset := maxD + 1
+	vf := make([]int, 2*offset+1)
+	vb := make([]int, 2*offset+1)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	odd := delta%2 != 0
+	for d := 0; d <= maxD; d++ {
+		// 前方探索
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vf[offset+k-1] < vf[offset+k+1]) {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			if x > n || y > m || x < 0 || y < 0 {
+				continue
+			}
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+			if odd {
+				if kb := delta - k; kb >= -(d-1) && kb <= d-1 && vb[offset+kb] >= 0 && x+vb[offset+kb] >= n {
+					return splitPoint(x, y, n, m)
+				}
+			}
+		}
+
+		// 後方探索 (末尾からの距離で管理する)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && vb[offset+k-1] < vb[offset+k+1]) {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			if x > n || y > m || x < 0 || y < 0 {
+				continue
+			}
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+			if !odd {
+				if kf := delta - k; kf >= -d && kf <= d && vf[offset+kf] >= 0 && vf[offset+kf]+x >= n {
+					return splitPoint(n-x, m-y, n, m)
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func splitPoint(x, y, n, m int) (int, int, bool) {
+	if (x == 0 && y == 0) || (x == n && y == m) {
+		return 0, 0, false
+	}
+	return x, y, true
+}
@@ -0,0 +1,674 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestParseUnifiedDiff(t *testing.T) {
+	type wantFile struct {
+		oldPath, newPath string
+		isNew, isDelete  bool
+		isRename         bool
+		isBinary         bool
+		newMode          string
+		hunks            int
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    []wantFile
+		wantErr string
+	}{
+		{
+			name: "git diff with multiple files",
+			patch: `diff --git a/main.go b/main.go
+index 83db48f..bf269f4 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@
+ package main
+-var a = 1
++var a = 2
+
+@@ -10,2 +10,3 @@ func main() {
+ 	run()
++	exit()
+ }
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
+-old
++new
+`,
+			want: []wantFile{
+				{oldPath: "main.go", newPath: "main.go", hunks: 2},
+				{oldPath: "README.md", newPath: "README.md", hunks: 1},
+			},
+		},
+		{
+			name: "plain unified diff with multiple files",
+			patch: `--- a/one.txt	2024-01-01 00:00:00
++++ b/one.txt	2024-01-02 00:00:00
+@@ -1 +1 @@
+-1
++one
+--- two.txt
++++ two.txt
+@@ -1 +1 @@
+-2
++two
+`,
+			want: []wantFile{
+				{oldPath: "one.txt", newPath: "one.txt", hunks: 1},
+				{oldPath: "two.txt", newPath: "two.txt", hunks: 1},
+			},
+		},
+		{
+			name: "new file",
+			patch: `diff --git a/cmd/run.sh b/cmd/run.sh
+new file mode 100755
+index 0000000..e69de29
+--- /dev/null
++++ b/cmd/run.sh
+@@ -0,0 +1,2 @@
++#!/bin/sh
++echo hi
+`,
+			want: []wantFile{
+				{newPath: "cmd/run.sh", isNew: true, newMode: "100755", hunks: 1},
+			},
+		},
+		{
+			name: "deleted file",
+			patch: `diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want: []wantFile{
+				{oldPath: "old.txt", newPath: "old.txt", isDelete: true, hunks: 1},
+			},
+		},
+		{
+			name: "pure rename",
+			patch: `diff --git a/src/a.go b/src/b.go
+similarity index 100%
+rename from src/a.go
+rename to src/b.go
+`,
+			want: []wantFile{
+				{oldPath: "src/a.go", newPath: "src/b.go", isRename: true},
+			},
+		},
+		{
+			name: "rename with changes",
+			patch: `diff --git a/a.txt b/b.txt
+similarity index 80%
+rename from a.txt
+rename to b.txt
+--- a/a.txt
++++ b/b.txt
+@@ -1,2 +1,2 @@
+ keep
+-old
++new
+`,
+			want: []wantFile{
+				{oldPath: "a.txt", newPath: "b.txt", isRename: true, hunks: 1},
+			},
+		},
+		{
+			name: "mode change only",
+			patch: `diff --git a/build.sh b/build.sh
+old mode 100644
+new mode 100755
+`,
+			want: []wantFile{
+				{oldPath: "build.sh", newPath: "build.sh", newMode: "100755"},
+			},
+		},
+		{
+			name: "binary file",
+			patch: `diff --git a/logo.png b/logo.png
+index 1111111..2222222 100644
+Binary files a/logo.png and b/logo.png differ
+`,
+			want: []wantFile{
+				{oldPath: "logo.png", newPath: "logo.png", isBinary: true},
+			},
+		},
+		{
+			name:  "CRLF line endings and leading text",
+			patch: "Subject: fix\r\n\r\n--- a/x.txt\r\n+++ b/x.txt\r\n@@ -1 +1 @@\r\n-a\r\n+b\r\n",
+			want: []wantFile{
+				{oldPath: "x.txt", newPath: "x.txt", hunks: 1},
+			},
+		},
+		{
+			name: "hunk header without closing marker",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1 +1
+-a
++b
+`,
+			wantErr: "invalid hunk header",
+		},
+		{
+			name: "hunk header with invalid numbers",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -a,1 +1 @@
+-a
++b
+`,
+			wantErr: "invalid hunk header",
+		},
+		{
+			name: "hunk header with a missing range",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 @@
+-a
+`,
+			wantErr: "invalid hunk header",
+		},
+		{
+			name: "hunk shorter than its header",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B`,
+			wantErr: "is truncated",
+		},
+		{
+			name: "unexpected line inside a hunk",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+diff --git a/y.txt b/y.txt
+`,
+			wantErr: "unexpected line in hunk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patches, err := parseUnifiedDiff(tt.patch)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(patches) != len(tt.want) {
+				t.Fatalf("got %d files, want %d", len(patches), len(tt.want))
+			}
+			for i, w := range tt.want {
+				p := patches[i]
+				got := wantFile{
+					oldPath:  p.OldPath,
+					newPath:  p.NewPath,
+					isNew:    p.IsNew,
+					isDelete: p.IsDelete,
+					isRename: p.IsRename,
+					isBinary: p.IsBinary,
+					newMode:  p.NewMode,
+					hunks:    len(p.Hunks),
+				}
+				if got != w {
+					t.Errorf("file %d = %+v, want %+v", i, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnifiedDiffNoNewline(t *testing.T) {
+	patches, err := parseUnifiedDiff(`--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++c
+\ No newline at end of file
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := patches[0].Hunks[0].Lines
+	if len(lines) != 3 {
+		t.Fatalf("got %d hunk lines, want 3", len(lines))
+	}
+	for i, want := range []bool{false, true, true} {
+		if lines[i].NoEOL != want {
+			t.Errorf("line %d (%c%s) NoEOL = %v, want %v", i, lines[i].Op, lines[i].Text, lines[i].NoEOL, want)
+		}
+	}
+}
+
+// applyPatchText は 1 ファイル分のパッチを内容に適用する
+func applyPatchText(t *testing.T, original, patch string, fuzz int) (string, []int, []hunkReject) {
+	t.Helper()
+	patches, err := parseUnifiedDiff(patch)
+	if err != nil {
+		t.Fatalf("parseUnifiedDiff: %v", err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %d files, want 1", len(patches))
+	}
+	lines, offsets, rejects := applyHunks("x.txt", splitFileLines(original), patches[0].Hunks, fuzz)
+	return joinFileLines(lines), offsets, rejects
+}
+
+func TestApplyHunks(t *testing.T) {
+	tests := []struct {
+		name        string
+		original    string
+		patch       string
+		fuzz        int
+		want        string
+		wantOffsets []int
+		wantRejects []int
+	}{
+		{
+			name:     "exact match",
+			original: "a\nb\nc\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			want:        "a\nB\nc\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "offset after lines were inserted above",
+			original: "x\ny\nz\na\nb\nc\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			want:        "x\ny\nz\na\nB\nc\n",
+			wantOffsets: []int{3},
+		},
+		{
+			name:     "negative offset after lines were removed above",
+			original: "a\nb\nc\nd\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -5,3 +5,3 @@
+ b
+-c
++C
+ d
+`,
+			want:        "a\nb\nC\nd\n",
+			wantOffsets: []int{-3},
+		},
+		{
+			name:     "trailing whitespace differences",
+			original: "a  \nb\nc\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			want:        "a\nB\nc\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "fuzz drops mismatched outer context",
+			original: "a\nb\nc\nd\ne\nf\ng\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -2,5 +2,5 @@
+ changed
+ c
+-d
++D
+ e
+ changed
+`,
+			fuzz:        1,
+			want:        "a\nb\nc\nD\ne\nf\ng\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "mismatched context without fuzz is rejected",
+			original: "a\nb\nc\nd\ne\nf\ng\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -2,5 +2,5 @@
+ changed
+ c
+-d
++D
+ e
+ changed
+`,
+			want:        "a\nb\nc\nd\ne\nf\ng\n",
+			wantOffsets: []int{},
+			wantRejects: []int{1},
+		},
+		{
+			name:     "multiple hunks shift later positions",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,4 @@
+ 1
++1a
++1b
+ 2
+@@ -7,3 +9,2 @@
+ 7
+-8
+ 9
+`,
+			want:        "1\n1a\n1b\n2\n3\n4\n5\n6\n7\n9\n",
+			wantOffsets: []int{0, 0},
+		},
+		{
+			name:     "only the hunk that does not match is rejected",
+			original: "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,2 @@
+-1
++one
+ 2
+@@ -7,3 +7,3 @@
+ seven
+-8
++eight
+ nine
+`,
+			want:        "one\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			wantOffsets: []int{0},
+			wantRejects: []int{2},
+		},
+		{
+			name:     "change a line without a trailing newline",
+			original: "a\nb",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++B
+\ No newline at end of file
+`,
+			want:        "a\nB",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "add a trailing newline",
+			original: "a\nb",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+			want:        "a\nb\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "remove the trailing newline",
+			original: "a\nb\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -1,2 +1,2 @@
+ a
+-b
++b
+\ No newline at end of file
+`,
+			want:        "a\nb",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "create a file",
+			original: "",
+			patch: `--- /dev/null
++++ b/x.txt
+@@ -0,0 +1,2 @@
++x
++y
+`,
+			want:        "x\ny\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "append to the end of a file",
+			original: "a\nb\n",
+			patch: `--- a/x.txt
++++ b/x.txt
+@@ -2,0 +3,1 @@
++c
+`,
+			want:        "a\nb\nc\n",
+			wantOffsets: []int{0},
+		},
+		{
+			name:     "delete every line",
+			original: "a\nb\n",
+			patch: `--- a/x.txt
++++ /dev/null
+@@ -1,2 +0,0 @@
+-a
+-b
+`,
+			want:        "",
+			wantOffsets: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, offsets, rejects := applyPatchText(t, tt.original, tt.patch, tt.fuzz)
+			// 不一致のハンクは適用せず、残りのハンクだけが反映される
+			if len(rejects) != len(tt.wantRejects) {
+				t.Fatalf("got rejects %+v, want hunks %v rejected", rejects, tt.wantRejects)
+			}
+			for i, r := range rejects {
+				if r.Hunk != tt.wantRejects[i] {
+					t.Errorf("reject %d is hunk %d, want %d", i, r.Hunk, tt.wantRejects[i])
+				}
+				if !strings.Contains(r.Reason, "context does not match") || len(r.Expected) == 0 {
+					t.Errorf("reject %d has no useful detail: %+v", i, r)
+				}
+			}
+			if got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+			if len(offsets) != len(tt.wantOffsets) {
+				t.Fatalf("offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+			for i := range offsets {
+				if offsets[i] != tt.wantOffsets[i] {
+					t.Errorf("offsets = %v, want %v", offsets, tt.wantOffsets)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLocateHunkPrefersNearestMatch(t *testing.T) {
+	lines := splitFileLines("x\nx\nx\nx\nx\nx\nx\n")
+	want := []patchLine{{Op: ' ', Text: "x"}, {Op: '-', Text: "x"}}
+
+	pos, trimHead, trimTail, ok := locateHunk(lines, want, 4, 0, 0)
+	if !ok || pos != 4 || trimHead != 0 || trimTail != 0 {
+		t.Errorf("locateHunk = (%d, %d, %d, %v), want (4, 0, 0, true)", pos, trimHead, trimTail, ok)
+	}
+
+	// 前のハンクより手前には戻らない
+	pos, _, _, ok = locateHunk(lines, want, 1, 3, 0)
+	if !ok || pos != 3 {
+		t.Errorf("locateHunk with minPos = (%d, %v), want (3, true)", pos, ok)
+	}
+
+	if _, _, _, ok := locateHunk(lines, []patchLine{{Op: '-', Text: "y"}}, 0, 0, defaultPatchFuzz); ok {
+		t.Error("locateHunk matched a line that does not exist")
+	}
+}
+
+func TestUnifiedDiffRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{name: "single change", old: "a\nb\nc\n", new: "a\nB\nc\n"},
+		{name: "insert at start", old: "b\nc\n", new: "a\nb\nc\n"},
+		{name: "append at end", old: "a\nb\n", new: "a\nb\nc\nd\n"},
+		{name: "delete lines", old: "a\nb\nc\nd\ne\n", new: "a\ne\n"},
+		{name: "replace everything", old: "a\nb\nc\n", new: "x\ny\n"},
+		{name: "empty to content", old: "", new: "a\nb\n"},
+		{name: "content to empty", old: "a\nb\n", new: ""},
+		{name: "empty to line without newline", old: "", new: "a"},
+		{name: "add trailing newline", old: "a\nb", new: "a\nb\n"},
+		{name: "remove trailing newline", old: "a\nb\n", new: "a\nb"},
+		{name: "change last line without newline", old: "a\nb", new: "a\nc"},
+		{name: "blank lines", old: "\n\n\n", new: "\nx\n\n"},
+		{
+			name: "distant changes make separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			new:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\nfifteen\n",
+		},
+		{
+			name: "nearby changes merge into one hunk",
+			old:  "1\n2\n3\n4\n5\n6\n7\n",
+			new:  "1\nII\n3\n4\nV\n6\n7\n",
+		},
+		{
+			name: "repeated lines",
+			old:  "x\nx\ny\nx\nx\ny\nx\n",
+			new:  "x\ny\nx\ny\ny\nx\nx\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, context := range []int{0, 1, 3} {
+				assertRoundTrip(t, tt.old, tt.new, context)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffNoChanges(t *testing.T) {
+	if d := unifiedDiff("x.txt", "a\nb\n", "a\nb\n", 3); d != "" {
+		t.Errorf("unifiedDiff of identical content = %q, want empty", d)
+	}
+}
+
+func TestUnifiedDiffLargeFile(t *testing.T) {
+	// 全行を比較する表を作らずに、散在する変更を最小限の行で表せること
+	var old, new strings.Builder
+	changed := 0
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&old, "line %d\n", i)
+		if i%100 == 0 {
+			fmt.Fprintf(&new, "LINE %d\n", i)
+			changed++
+			continue
+		}
+		fmt.Fprintf(&new, "line %d\n", i)
+	}
+
+	d := assertRoundTrip(t, old.String(), new.String(), 3)
+	removed, added := 0, 0
+	for _, l := range strings.Split(d, "\n") {
+		switch {
+		case strings.HasPrefix(l, "---"), strings.HasPrefix(l, "+++"):
+		case strings.HasPrefix(l, "-"):
+			removed++
+		case strings.HasPrefix(l, "+"):
+			added++
+		}
+	}
+	if removed != changed || added != changed {
+		t.Errorf("diff has -%d +%d lines, want -%d +%d", removed, added, changed, changed)
+	}
+}
+
+func TestUnifiedDiffRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"a", "b", "c", "d", ""}
+	randomContent := func() string {
+		var b strings.Builder
+		n := rng.Intn(12)
+		for i := 0; i < n; i++ {
+			b.WriteString(words[rng.Intn(len(words))])
+			if i < n-1 || rng.Intn(3) > 0 {
+				b.WriteString("\n")
+			}
+		}
+		return b.String()
+	}
+
+	for i := 0; i < 500; i++ {
+		assertRoundTrip(t, randomContent(), randomContent(), rng.Intn(4))
+	}
+}
+
+// assertRoundTrip は unifiedDiff の出力を applyHunks で適用して新しい内容に戻ることを確かめる
+func assertRoundTrip(t *testing.T, oldContent, newContent string, context int) string {
+	t.Helper()
+	d := unifiedDiff("x.txt", oldContent, newContent, context)
+	if d == "" {
+		if oldContent != newContent {
+			t.Fatalf("unifiedDiff(%q, %q) returned no diff", oldContent, newContent)
+		}
+		return d
+	}
+
+	patches, err := parseUnifiedDiff(d)
+	if err != nil {
+		t.Fatalf("parseUnifiedDiff failed for %q -> %q: %v\n%s", oldContent, newContent, err, d)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("got %d files, want 1\n%s", len(patches), d)
+	}
+	lines, _, rejects := applyHunks("x.txt", splitFileLines(oldContent), patches[0].Hunks, 0)
+	if len(rejects) > 0 {
+		t.Fatalf("hunks rejected for %q -> %q: %+v\n%s", oldContent, newContent, rejects, d)
+	}
+	if got := joinFileLines(lines); got != newContent {
+		t.Fatalf("round trip of %q -> %q (context %d) = %q\n%s", oldContent, newContent, context, got, d)
+	}
+	return d
+}
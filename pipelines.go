@@ -0,0 +1,569 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// スタックトレースのデフォルト最大文字数
+const defaultMaxStackTraceChars = 2000
+
+const (
+	// パイプライン待機のデフォルトタイムアウト
+	defaultPipelineWaitTimeout = 10 * time.Minute
+	// ポーリング間隔の初期値と上限
+	pipelinePollInitialInterval = 5 * time.Second
+	pipelinePollMaxInterval     = 30 * time.Second
+)
+
+// パイプラインがこれ以上進まない状態
+var terminalPipelineStatuses = map[string]bool{
+	"success":  true,
+	"failed":   true,
+	"canceled": true,
+	"skipped":  true,
+	"manual":   true,
+}
+
+func registerPipelineTools(s *server.MCPServer) {
+	// テストレポート取得
+	s.AddTool(
+		mcp.NewTool("get_test_report",
+			mcp.WithDescription("Get a summarized JUnit test report for a pipeline: failures with messages and stack traces, flakiness hints and per-job coverage"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("pipeline_id",
+				mcp.Description("Pipeline ID"),
+			),
+			mcp.WithNumber("merge_request_iid",
+				mcp.Description("Merge request IID (uses the MR's head pipeline)"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch or tag name (uses the latest pipeline on the ref)"),
+			),
+			mcp.WithNumber("max_failures",
+				mcp.Description("Maximum number of failed test cases to return (default: 50)"),
+			),
+			mcp.WithNumber("max_stack_trace_chars",
+				mcp.Description("Maximum characters of each stack trace and message (default: 2000)"),
+			),
+		),
+		handleGetTestReport,
+	)
+
+	// CI 設定の検証
+	s.AddTool(
+		mcp.NewTool("lint_ci_config",
+			mcp.WithDescription("Validate a .gitlab-ci.yml configuration with the project's CI lint API, from supplied content or from a ref"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("content",
+				mcp.Description("CI configuration content to validate (if omitted, the configuration at 'ref' is validated)"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch or tag used as context for includes and rules (default: default branch)"),
+			),
+			mcp.WithBoolean("dry_run",
+				mcp.Description("Simulate pipeline creation for a more thorough validation (default: false)"),
+			),
+			mcp.WithBoolean("include_merged_yaml",
+				mcp.Description("Return the merged/expanded YAML (default: true)"),
+			),
+		),
+		handleLintCIConfig,
+	)
+
+	// パイプライン完了待ち
+	s.AddTool(
+		mcp.NewTool("wait_for_pipeline",
+			mcp.WithDescription("Wait for a pipeline to finish, polling with backoff and sending progress notifications with per-stage status"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithNumber("pipeline_id",
+				mcp.Description("Pipeline ID"),
+			),
+			mcp.WithNumber("merge_request_iid",
+				mcp.Description("Merge request IID (waits for the MR's head pipeline)"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch or tag name (waits for the latest pipeline on the ref)"),
+			),
+			mcp.WithNumber("timeout_seconds",
+				mcp.Description("Maximum time to wait in seconds (default: 600)"),
+			),
+		),
+		handleWaitForPipeline,
+	)
+}
+
+func handleGetTestReport(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	maxFailures := getInt(args, "max_failures", 50)
+	maxTraceChars := getInt(args, "max_stack_trace_chars", defaultMaxStackTraceChars)
+
+	pipeline, err := resolvePipeline(projectID, args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve pipeline: %v", err)), nil
+	}
+
+	report, _, err := gitlabClient.Pipelines.GetPipelineTestReport(projectID, pipeline.ID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get test report: %v", err)), nil
+	}
+
+	// 失敗したテストケースを収集
+	var failures []map[string]interface{}
+	var flakyHints []map[string]interface{}
+	omitted := 0
+	for _, suite := range report.TestSuites {
+		for _, tc := range suite.TestCases {
+			if tc.Status != "failed" && tc.Status != "error" {
+				continue
+			}
+
+			if tc.RecentFailures != nil && tc.RecentFailures.Count > 0 {
+				flakyHints = append(flakyHints, map[string]interface{}{
+					"suite":           suite.Name,
+					"name":            tc.Name,
+					"classname":       tc.Classname,
+					"recent_failures": tc.RecentFailures.Count,
+					"base_branch":     tc.RecentFailures.BaseBranch,
+				})
+			}
+
+			if len(failures) >= maxFailures {
+				omitted++
+				continue
+			}
+
+			failures = append(failures, map[string]interface{}{
+				"suite":          suite.Name,
+				"name":           tc.Name,
+				"classname":      tc.Classname,
+				"file":           tc.File,
+				"status":         tc.Status,
+				"execution_time": tc.ExecutionTime,
+				"message":        truncateString(systemOutputString(tc.SystemOutput), maxTraceChars),
+				"stack_trace":    truncateString(tc.StackTrace, maxTraceChars),
+			})
+		}
+	}
+
+	// リトライされたジョブとカバレッジを収集
+	jobs, err := listAllPipelineJobs(ctx, projectID, pipeline.ID, true)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list pipeline jobs: %v", err)), nil
+	}
+
+	attempts := make(map[string][]*gitlab.Job)
+	var coverage []map[string]interface{}
+	for _, j := range jobs {
+		attempts[j.Name] = append(attempts[j.Name], j)
+	}
+
+	var retriedJobs []map[string]interface{}
+	for name, js := range attempts {
+		// ID の昇順 = 実行順
+		sort.Slice(js, func(a, b int) bool { return js[a].ID < js[b].ID })
+
+		latest := js[len(js)-1]
+		if latest.Coverage > 0 {
+			coverage = append(coverage, map[string]interface{}{
+				"job":      name,
+				"job_id":   latest.ID,
+				"coverage": latest.Coverage,
+			})
+		}
+
+		if len(js) < 2 {
+			continue
+		}
+
+		statuses := make([]string, len(js))
+		failedBefore := false
+		for i, j := range js {
+			statuses[i] = j.Status
+			if i < len(js)-1 && j.Status == "failed" {
+				failedBefore = true
+			}
+		}
+		retriedJobs = append(retriedJobs, map[string]interface{}{
+			"job":      name,
+			"attempts": statuses,
+			// 失敗後のリトライで成功したジョブは不安定なテストの可能性が高い
+			"flaky": failedBefore && latest.Status == "success",
+		})
+	}
+	sort.Slice(coverage, func(a, b int) bool { return coverage[a]["job"].(string) < coverage[b]["job"].(string) })
+	sort.Slice(retriedJobs, func(a, b int) bool { return retriedJobs[a]["job"].(string) < retriedJobs[b]["job"].(string) })
+
+	result := map[string]interface{}{
+		"pipeline_id":      pipeline.ID,
+		"pipeline_status":  pipeline.Status,
+		"ref":              pipeline.Ref,
+		"sha":              pipeline.SHA,
+		"web_url":          pipeline.WebURL,
+		"total_count":      report.TotalCount,
+		"success_count":    report.SuccessCount,
+		"failed_count":     report.FailedCount,
+		"error_count":      report.ErrorCount,
+		"skipped_count":    report.SkippedCount,
+		"total_time":       report.TotalTime,
+		"failures":         failures,
+		"failures_omitted": omitted,
+		"flaky_hints":      flakyHints,
+		"retried_jobs":     retriedJobs,
+		"coverage":         pipeline.Coverage,
+		"job_coverage":     coverage,
+	}
+
+	return jsonResult(result)
+}
+
+func handleLintCIConfig(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	content := getString(args, "content", "")
+	ref := getString(args, "ref", "")
+	dryRun := getBool(args, "dry_run", false)
+	includeMergedYAML := getBool(args, "include_merged_yaml", true)
+
+	var lint *gitlab.ProjectLintResult
+	var err error
+	if content != "" {
+		lint, err = lintCIContent(projectID, content, ref, dryRun)
+	} else {
+		opts := &gitlab.ProjectLintOptions{
+			DryRun: gitlab.Ptr(dryRun),
+		}
+		if ref != "" {
+			opts.ContentRef = gitlab.Ptr(ref)
+			if dryRun {
+				opts.DryRunRef = gitlab.Ptr(ref)
+			}
+		}
+		lint, _, err = gitlabClient.Validate.ProjectLint(projectID, opts)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to lint CI config: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"valid":    lint.Valid,
+		"errors":   lint.Errors,
+		"warnings": lint.Warnings,
+	}
+	if includeMergedYAML {
+		result["merged_yaml"] = lint.MergedYaml
+	}
+
+	return jsonResult(result)
+}
+
+// lintCIContent は与えられた CI 設定の内容をプロジェクトのコンテキストで検証する
+func lintCIContent(projectID, content, ref string, dryRun bool) (*gitlab.ProjectLintResult, error) {
+	opts := &gitlab.ProjectNamespaceLintOptions{
+		Content: gitlab.Ptr(content),
+		DryRun:  gitlab.Ptr(dryRun),
+	}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+
+	lint, _, err := gitlabClient.Validate.ProjectNamespaceLint(projectID, opts)
+	return lint, err
+}
+
+func handleWaitForPipeline(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	timeout := defaultPipelineWaitTimeout
+	if seconds := getInt(args, "timeout_seconds", 0); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	pipeline, err := resolvePipeline(projectID, args)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to resolve pipeline: %v", err)), nil
+	}
+
+	var progressToken mcp.ProgressToken
+	if req.Params.Meta != nil {
+		progressToken = req.Params.Meta.ProgressToken
+	}
+
+	deadline := time.Now().Add(timeout)
+	interval := pipelinePollInitialInterval
+	var jobs []*gitlab.Job
+	timedOut := false
+	lastProgress := -1
+
+	for {
+		jobs, err = listAllPipelineJobs(ctx, projectID, pipeline.ID, false)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list pipeline jobs: %v", err)), nil
+		}
+
+		lastProgress = sendPipelineProgress(ctx, progressToken, pipeline, jobs, lastProgress)
+
+		if terminalPipelineStatuses[pipeline.Status] {
+			break
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			timedOut = true
+			break
+		}
+
+		// 期限の直前でも最後にもう一度状態を確認できるよう、待機は残り時間までにする
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+
+		select {
+		case <-ctx.Done():
+			return mcp.NewToolResultError(fmt.Sprintf("Waiting for pipeline %d was cancelled", pipeline.ID)), nil
+		case <-time.After(wait):
+		}
+
+		// 指数バックオフ (上限あり)
+		interval = interval * 3 / 2
+		if interval > pipelinePollMaxInterval {
+			interval = pipelinePollMaxInterval
+		}
+
+		pipeline, _, err = gitlabClient.Pipelines.GetPipeline(projectID, pipeline.ID, gitlab.WithContext(ctx))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get pipeline: %v", err)), nil
+		}
+	}
+
+	var failedJobs []map[string]interface{}
+	for _, j := range jobs {
+		if j.Status != "failed" {
+			continue
+		}
+		failedJobs = append(failedJobs, map[string]interface{}{
+			"id":             j.ID,
+			"name":           j.Name,
+			"stage":          j.Stage,
+			"allow_failure":  j.AllowFailure,
+			"failure_reason": j.FailureReason,
+			"web_url":        j.WebURL,
+		})
+	}
+
+	result := map[string]interface{}{
+		"pipeline_id": pipeline.ID,
+		"status":      pipeline.Status,
+		"timed_out":   timedOut,
+		"ref":         pipeline.Ref,
+		"sha":         pipeline.SHA,
+		"duration":    pipeline.Duration,
+		"coverage":    pipeline.Coverage,
+		"stages":      stageStatuses(jobs),
+		"failed_jobs": failedJobs,
+		"web_url":     pipeline.WebURL,
+	}
+
+	return jsonResult(result)
+}
+
+// sendPipelineProgress は完了ジョブ数を進捗としてクライアントに通知し、通知した進捗を返す。
+// MCP の進捗は増加し続ける必要があるため、lastProgress を超えない場合は通知しない
+func sendPipelineProgress(ctx context.Context, token mcp.ProgressToken, pipeline *gitlab.Pipeline, jobs []*gitlab.Job, lastProgress int) int {
+	if token == nil {
+		return lastProgress
+	}
+	srv := server.ServerFromContext(ctx)
+	if srv == nil {
+		return lastProgress
+	}
+
+	finished := 0
+	for _, j := range jobs {
+		if j.FinishedAt != nil {
+			finished++
+		}
+	}
+	if finished <= lastProgress {
+		return lastProgress
+	}
+
+	var stages []string
+	for _, st := range stageStatuses(jobs) {
+		stages = append(stages, fmt.Sprintf("%s: %s", st["stage"], st["status"]))
+	}
+
+	// 通知の失敗は待機処理に影響させない
+	_ = srv.SendNotificationToClient(ctx, "notifications/progress", map[string]any{
+		"progressToken": token,
+		"progress":      finished,
+		"total":         len(jobs),
+		"message":       fmt.Sprintf("pipeline %d %s (%s)", pipeline.ID, pipeline.Status, strings.Join(stages, ", ")),
+	})
+	return finished
+}
+
+// stageStatuses はジョブをステージごとにまとめ、ステージの状態を導出する
+func stageStatuses(jobs []*gitlab.Job) []map[string]interface{} {
+	var order []string
+	byStage := make(map[string][]*gitlab.Job)
+	firstID := make(map[string]int)
+	for _, j := range jobs {
+		if _, ok := byStage[j.Stage]; !ok {
+			order = append(order, j.Stage)
+			firstID[j.Stage] = j.ID
+		}
+		byStage[j.Stage] = append(byStage[j.Stage], j)
+		firstID[j.Stage] = min(firstID[j.Stage], j.ID)
+	}
+
+	// ジョブはパイプライン作成時にステージ順で作られるため、各ステージの最小のジョブ ID で並べる
+	// (リトライされたジョブは ID が大きくなるが最小値には影響しない)
+	sort.Slice(order, func(a, b int) bool { return firstID[order[a]] < firstID[order[b]] })
+
+	result := make([]map[string]interface{}, 0, len(order))
+	for _, stage := range order {
+		counts := make(map[string]int)
+		hardFailures := 0
+		for _, j := range byStage[stage] {
+			counts[j.Status]++
+			if j.Status == "failed" && !j.AllowFailure {
+				hardFailures++
+			}
+		}
+
+		status := "success"
+		switch {
+		case hardFailures > 0:
+			status = "failed"
+		case counts["running"] > 0:
+			status = "running"
+		case counts["pending"] > 0 || counts["created"] > 0 || counts["preparing"] > 0 || counts["waiting_for_resource"] > 0:
+			status = "pending"
+		case counts["manual"] > 0:
+			status = "manual"
+		case counts["canceled"] > 0:
+			status = "canceled"
+		case counts["skipped"] == len(byStage[stage]):
+			status = "skipped"
+		}
+
+		result = append(result, map[string]interface{}{
+			"stage":  stage,
+			"status": status,
+			"jobs":   counts,
+		})
+	}
+	return result
+}
+
+// resolvePipeline は pipeline_id / merge_request_iid / ref のいずれかから対象パイプラインを取得する
+func resolvePipeline(projectID string, args map[string]interface{}) (*gitlab.Pipeline, error) {
+	if pipelineID := getInt(args, "pipeline_id", 0); pipelineID > 0 {
+		pipeline, _, err := gitlabClient.Pipelines.GetPipeline(projectID, pipelineID)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline %d: %v", pipelineID, err)
+		}
+		return pipeline, nil
+	}
+
+	if mrIID := getInt(args, "merge_request_iid", 0); mrIID > 0 {
+		mr, _, err := gitlabClient.MergeRequests.GetMergeRequest(projectID, mrIID, nil)
+		if err != nil {
+			return nil, fmt.Errorf("merge request !%d: %v", mrIID, err)
+		}
+		if mr.HeadPipeline == nil {
+			return nil, fmt.Errorf("merge request !%d has no pipeline", mrIID)
+		}
+		return mr.HeadPipeline, nil
+	}
+
+	if ref := getString(args, "ref", ""); ref != "" {
+		pipeline, _, err := gitlabClient.Pipelines.GetLatestPipeline(projectID, &gitlab.GetLatestPipelineOptions{
+			Ref: gitlab.Ptr(ref),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("latest pipeline for %s: %v", ref, err)
+		}
+		return pipeline, nil
+	}
+
+	return nil, fmt.Errorf("one of pipeline_id, merge_request_iid or ref is required")
+}
+
+// listAllPipelineJobs はパイプラインの全ジョブをページをたどって取得する
+func listAllPipelineJobs(ctx context.Context, projectID string, pipelineID int, includeRetried bool) ([]*gitlab.Job, error) {
+	opts := &gitlab.ListJobsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		IncludeRetried: gitlab.Ptr(includeRetried),
+	}
+
+	var all []*gitlab.Job
+	for {
+		jobs, resp, err := gitlabClient.Jobs.ListPipelineJobs(projectID, pipelineID, opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, jobs...)
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return all, nil
+}
+
+// systemOutputString はテストケースの system_output を文字列に変換する
+func systemOutputString(v interface{}) string {
+	switch out := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return out
+	case []interface{}:
+		var s string
+		for i, line := range out {
+			if i > 0 {
+				s += "\n"
+			}
+			s += fmt.Sprint(line)
+		}
+		return s
+	default:
+		return fmt.Sprint(out)
+	}
+}
@@ -0,0 +1,867 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// releaseDetails は go-gitlab の Release に含まれない milestones を加えたもの
+type releaseDetails struct {
+	gitlab.Release
+	Milestones []struct {
+		Title string `json:"title"`
+		State string `json:"state"`
+	} `json:"milestones"`
+}
+
+// releaseNoteCategory はリリースノートの見出しと対応するラベル
+type releaseNoteCategory struct {
+	Title  string
+	Labels []string
+}
+
+// リリースノートのデフォルトの分類
+var defaultReleaseNoteCategories = []releaseNoteCategory{
+	{Title: "Features", Labels: []string{"feature", "enhancement", "type::feature"}},
+	{Title: "Bug fixes", Labels: []string{"bug", "type::bug"}},
+	{Title: "Maintenance", Labels: []string{"chore", "maintenance", "documentation", "type::maintenance"}},
+}
+
+func registerReleaseTools(s *server.MCPServer) {
+	// タグ一覧取得
+	s.AddTool(
+		mcp.NewTool("list_tags",
+			mcp.WithDescription("List repository tags in a GitLab project"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("search",
+				mcp.Description("Filter tags by name ('^v1' for prefix, '-rc$' for suffix)"),
+			),
+			mcp.WithString("order_by",
+				mcp.Description("Order by: name, updated, version (default: updated)"),
+			),
+			mcp.WithString("sort",
+				mcp.Description("Sort order: asc, desc (default: desc)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of tags per page (default: 20)"),
+			),
+		),
+		handleListTags,
+	)
+
+	// タグ作成
+	s.AddTool(
+		mcp.NewTool("create_tag",
+			mcp.WithDescription("Create a tag; with a message it becomes an annotated tag"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("tag_name",
+				mcp.Required(),
+				mcp.Description("Name of the tag"),
+			),
+			mcp.WithString("ref",
+				mcp.Required(),
+				mcp.Description("Branch name or commit SHA to tag"),
+			),
+			mcp.WithString("message",
+				mcp.Description("Message for an annotated tag"),
+			),
+		),
+		handleCreateTag,
+	)
+
+	// タグ削除
+	s.AddTool(
+		mcp.NewTool("delete_tag",
+			mcp.WithDescription("Delete a tag"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("tag_name",
+				mcp.Required(),
+				mcp.Description("Name of the tag"),
+			),
+		),
+		handleDeleteTag,
+	)
+
+	// リリース一覧取得
+	s.AddTool(
+		mcp.NewTool("list_releases",
+			mcp.WithDescription("List releases in a GitLab project"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("order_by",
+				mcp.Description("Order by: released_at, created_at (default: released_at)"),
+			),
+			mcp.WithString("sort",
+				mcp.Description("Sort order: asc, desc (default: desc)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of releases per page (default: 20)"),
+			),
+		),
+		handleListReleases,
+	)
+
+	// リリース詳細取得
+	s.AddTool(
+		mcp.NewTool("get_release",
+			mcp.WithDescription("Get a release with its notes, milestones and asset links"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("tag_name",
+				mcp.Required(),
+				mcp.Description("Tag the release is associated with"),
+			),
+		),
+		handleGetRelease,
+	)
+
+	// リリース作成
+	s.AddTool(
+		mcp.NewTool("create_release",
+			mcp.WithDescription("Create a release. The tag is created from ref if it does not exist yet"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("tag_name",
+				mcp.Required(),
+				mcp.Description("Tag for the release"),
+			),
+			mcp.WithString("name",
+				mcp.Description("Release name (default: tag name)"),
+			),
+			mcp.WithString("description",
+				mcp.Description("Release notes (Markdown)"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch or commit SHA to create the tag from, when the tag does not exist"),
+			),
+			mcp.WithString("tag_message",
+				mcp.Description("Message for the annotated tag created with the release"),
+			),
+			mcp.WithString("milestones",
+				mcp.Description("Comma-separated milestone titles to associate"),
+			),
+			mcp.WithArray("asset_links",
+				mcp.Description("Array of asset links, each with 'name', 'url' and optional 'filepath' and 'link_type' (other, runbook, image, package)"),
+			),
+			mcp.WithString("released_at",
+				mcp.Description("Release date (ISO 8601); a future date creates an upcoming release"),
+			),
+		),
+		handleCreateRelease,
+	)
+
+	// リリース更新
+	s.AddTool(
+		mcp.NewTool("update_release",
+			mcp.WithDescription("Update a release's name, notes, milestones or release date, and add asset links"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("tag_name",
+				mcp.Required(),
+				mcp.Description("Tag the release is associated with"),
+			),
+			mcp.WithString("name",
+				mcp.Description("New release name"),
+			),
+			mcp.WithString("description",
+				mcp.Description("New release notes (Markdown)"),
+			),
+			mcp.WithString("milestones",
+				mcp.Description("Comma-separated milestone titles; replaces the current milestones"),
+			),
+			mcp.WithArray("asset_links",
+				mcp.Description("Asset links to add, each with 'name', 'url' and optional 'filepath' and 'link_type'"),
+			),
+			mcp.WithString("released_at",
+				mcp.Description("Release date (ISO 8601)"),
+			),
+		),
+		handleUpdateRelease,
+	)
+
+	// リリースノート生成
+	s.AddTool(
+		mcp.NewTool("generate_release_notes",
+			mcp.WithDescription("Generate Markdown release notes from merge requests merged between two refs, grouped by label with authors and closed issues"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("from",
+				mcp.Required(),
+				mcp.Description("Previous release tag, branch or commit SHA"),
+			),
+			mcp.WithString("to",
+				mcp.Description("New release ref (default: default branch)"),
+			),
+			mcp.WithString("version",
+				mcp.Description("Version used in the heading (default: value of 'to')"),
+			),
+			mcp.WithArray("categories",
+				mcp.Description("Ordered label mapping, each with 'title' and 'labels' (e.g., [{\"title\": \"Features\", \"labels\": [\"feature\"]}]). Default: Features (feature, enhancement), Bug fixes (bug), Maintenance (chore, maintenance, documentation)"),
+			),
+			mcp.WithBoolean("use_changelog_api",
+				mcp.Description("Use GitLab's changelog API (commit trailers) instead of merge requests (default: false)"),
+			),
+			mcp.WithString("trailer",
+				mcp.Description("Commit trailer used by the changelog API (default: Changelog)"),
+			),
+			mcp.WithNumber("max_commits",
+				mcp.Description("Maximum number of commits to inspect for merge requests (default: 500)"),
+			),
+		),
+		handleGenerateReleaseNotes,
+	)
+}
+
+func handleListTags(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	perPage := getInt(args, "per_page", 20)
+
+	opts := &gitlab.ListTagsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+		},
+	}
+	if search := getString(args, "search", ""); search != "" {
+		opts.Search = gitlab.Ptr(search)
+	}
+	if orderBy := getString(args, "order_by", ""); orderBy != "" {
+		opts.OrderBy = gitlab.Ptr(orderBy)
+	}
+	if sort := getString(args, "sort", ""); sort != "" {
+		opts.Sort = gitlab.Ptr(sort)
+	}
+
+	tags, _, err := gitlabClient.Tags.ListTags(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list tags: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(tags))
+	for i, t := range tags {
+		result[i] = tagSummary(t)
+	}
+
+	return jsonResult(result)
+}
+
+func handleCreateTag(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	tagName, ok := args["tag_name"].(string)
+	if !ok || tagName == "" {
+		return mcp.NewToolResultError("tag_name is required"), nil
+	}
+
+	ref, ok := args["ref"].(string)
+	if !ok || ref == "" {
+		return mcp.NewToolResultError("ref is required"), nil
+	}
+
+	opts := &gitlab.CreateTagOptions{
+		TagName: gitlab.Ptr(tagName),
+		Ref:     gitlab.Ptr(ref),
+	}
+	if message := getString(args, "message", ""); message != "" {
+		opts.Message = gitlab.Ptr(message)
+	}
+
+	tag, _, err := gitlabClient.Tags.CreateTag(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create tag: %v", err)), nil
+	}
+
+	return jsonResult(tagSummary(tag))
+}
+
+func handleDeleteTag(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	tagName, ok := args["tag_name"].(string)
+	if !ok || tagName == "" {
+		return mcp.NewToolResultError("tag_name is required"), nil
+	}
+
+	_, err := gitlabClient.Tags.DeleteTag(projectID, tagName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete tag: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action":   "deleted",
+		"tag_name": tagName,
+	}
+	return jsonResult(result)
+}
+
+func handleListReleases(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	perPage := getInt(args, "per_page", 20)
+
+	opts := &gitlab.ListReleasesOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+		},
+	}
+	if orderBy := getString(args, "order_by", ""); orderBy != "" {
+		opts.OrderBy = gitlab.Ptr(orderBy)
+	}
+	if sort := getString(args, "sort", ""); sort != "" {
+		opts.Sort = gitlab.Ptr(sort)
+	}
+
+	releases, _, err := gitlabClient.Releases.ListReleases(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list releases: %v", err)), nil
+	}
+
+	result := make([]map[string]interface{}, len(releases))
+	for i, r := range releases {
+		result[i] = map[string]interface{}{
+			"tag_name":         r.TagName,
+			"name":             r.Name,
+			"released_at":      r.ReleasedAt,
+			"upcoming_release": r.UpcomingRelease,
+			"author":           r.Author.Username,
+			"commit":           r.Commit.ShortID,
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleGetRelease(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	tagName, ok := args["tag_name"].(string)
+	if !ok || tagName == "" {
+		return mcp.NewToolResultError("tag_name is required"), nil
+	}
+
+	release, err := getReleaseDetails(projectID, tagName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get release: %v", err)), nil
+	}
+
+	return jsonResult(releaseSummary(release))
+}
+
+func handleCreateRelease(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	tagName, ok := args["tag_name"].(string)
+	if !ok || tagName == "" {
+		return mcp.NewToolResultError("tag_name is required"), nil
+	}
+
+	opts := &gitlab.CreateReleaseOptions{
+		TagName: gitlab.Ptr(tagName),
+	}
+	if name := getString(args, "name", ""); name != "" {
+		opts.Name = gitlab.Ptr(name)
+	}
+	if desc := getString(args, "description", ""); desc != "" {
+		opts.Description = gitlab.Ptr(desc)
+	}
+	if ref := getString(args, "ref", ""); ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	if tagMessage := getString(args, "tag_message", ""); tagMessage != "" {
+		opts.TagMessage = gitlab.Ptr(tagMessage)
+	}
+	if milestones := getString(args, "milestones", ""); milestones != "" {
+		opts.Milestones = gitlab.Ptr(splitLabels(milestones))
+	}
+	if releasedAt := getString(args, "released_at", ""); releasedAt != "" {
+		t, err := parseTimeArg(releasedAt)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid released_at: %v", err)), nil
+		}
+		opts.ReleasedAt = gitlab.Ptr(t)
+	}
+
+	links, errResult := parseAssetLinks(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	if len(links) > 0 {
+		assets := &gitlab.ReleaseAssetsOptions{}
+		for _, l := range links {
+			assets.Links = append(assets.Links, &gitlab.ReleaseAssetLinkOptions{
+				Name:     l.Name,
+				URL:      l.URL,
+				FilePath: l.FilePath,
+				LinkType: l.LinkType,
+			})
+		}
+		opts.Assets = assets
+	}
+
+	if _, _, err := gitlabClient.Releases.CreateRelease(projectID, opts); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create release: %v", err)), nil
+	}
+
+	release, err := getReleaseDetails(projectID, tagName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Release created but failed to get it: %v", err)), nil
+	}
+
+	return jsonResult(releaseSummary(release))
+}
+
+func handleUpdateRelease(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	tagName, ok := args["tag_name"].(string)
+	if !ok || tagName == "" {
+		return mcp.NewToolResultError("tag_name is required"), nil
+	}
+
+	links, errResult := parseAssetLinks(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	// UpdateReleaseOptions の name と description は省略すると null が送られるため現在の値で補う
+	current, _, err := gitlabClient.Releases.GetRelease(projectID, tagName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get release: %v", err)), nil
+	}
+
+	opts := &gitlab.UpdateReleaseOptions{
+		Name:        gitlab.Ptr(getString(args, "name", current.Name)),
+		Description: gitlab.Ptr(getString(args, "description", current.Description)),
+	}
+	if milestones, ok := args["milestones"].(string); ok {
+		opts.Milestones = gitlab.Ptr(splitLabels(milestones))
+	}
+	if releasedAt := getString(args, "released_at", ""); releasedAt != "" {
+		t, err := parseTimeArg(releasedAt)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid released_at: %v", err)), nil
+		}
+		opts.ReleasedAt = gitlab.Ptr(t)
+	}
+
+	if _, _, err := gitlabClient.Releases.UpdateRelease(projectID, tagName, opts); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to update release: %v", err)), nil
+	}
+
+	for _, l := range links {
+		if _, _, err := gitlabClient.ReleaseLinks.CreateReleaseLink(projectID, tagName, l); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Release updated but failed to add asset link %s: %v", *l.Name, err)), nil
+		}
+	}
+
+	// 追加したリンクを含めて返す
+	release, err := getReleaseDetails(projectID, tagName)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Release updated but failed to get it: %v", err)), nil
+	}
+
+	return jsonResult(releaseSummary(release))
+}
+
+func handleGenerateReleaseNotes(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	from, ok := args["from"].(string)
+	if !ok || from == "" {
+		return mcp.NewToolResultError("from is required"), nil
+	}
+
+	maxCommits := getInt(args, "max_commits", 500)
+	if maxCommits < 1 {
+		return mcp.NewToolResultError("max_commits must be at least 1"), nil
+	}
+
+	to := getString(args, "to", "")
+	if to == "" {
+		project, _, err := gitlabClient.Projects.GetProject(projectID, nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get project: %v", err)), nil
+		}
+		to = project.DefaultBranch
+	}
+	version := getString(args, "version", to)
+
+	if getBool(args, "use_changelog_api", false) {
+		notes, err := generateChangelog(projectID, version, from, to, getString(args, "trailer", ""))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to generate changelog: %v", err)), nil
+		}
+		result := map[string]interface{}{
+			"from":     from,
+			"to":       to,
+			"version":  version,
+			"source":   "changelog_api",
+			"markdown": notes,
+		}
+		return jsonResult(result)
+	}
+
+	categories := defaultReleaseNoteCategories
+	if categoriesArg, ok := args["categories"].([]interface{}); ok && len(categoriesArg) > 0 {
+		categories = nil
+		for i, item := range categoriesArg {
+			c, ok := item.(map[string]interface{})
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("categories[%d] must be an object", i)), nil
+			}
+			title := getString(c, "title", "")
+			labelsArg, _ := c["labels"].([]interface{})
+			if title == "" || len(labelsArg) == 0 {
+				return mcp.NewToolResultError(fmt.Sprintf("categories[%d] requires title and labels", i)), nil
+			}
+			category := releaseNoteCategory{Title: title}
+			for _, l := range labelsArg {
+				if label, ok := l.(string); ok {
+					category.Labels = append(category.Labels, label)
+				}
+			}
+			categories = append(categories, category)
+		}
+	}
+
+	compare, _, err := gitlabClient.Repositories.Compare(projectID, &gitlab.CompareOptions{
+		From: gitlab.Ptr(from),
+		To:   gitlab.Ptr(to),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to compare refs: %v", err)), nil
+	}
+
+	commits := compare.Commits
+	commitsOmitted := 0
+	if len(commits) > maxCommits {
+		commitsOmitted = len(commits) - maxCommits
+		commits = commits[:maxCommits]
+	}
+
+	mrs, err := mergedMergeRequestsForCommits(projectID, commits)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to find merge requests: %v", err)), nil
+	}
+
+	// MR ごとにクローズした Issue を取得する
+	closedIssues := make(map[int][]*gitlab.Issue)
+	for _, mr := range mrs {
+		issues, _, err := gitlabClient.MergeRequests.GetIssuesClosedOnMerge(projectID, mr.IID, nil)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get issues closed by !%d: %v", mr.IID, err)), nil
+		}
+		closedIssues[mr.IID] = issues
+	}
+
+	// 最初に一致した分類に振り分け、どれにも一致しなければ Other changes とする
+	grouped := make([][]*gitlab.MergeRequest, len(categories)+1)
+	for _, mr := range mrs {
+		index := len(categories)
+		for i, c := range categories {
+			if hasAnyLabel(mr.Labels, c.Labels) {
+				index = i
+				break
+			}
+		}
+		grouped[index] = append(grouped[index], mr)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s (%s)\n", version, time.Now().Format("2006-01-02"))
+
+	var contributors []string
+	seenAuthors := make(map[string]bool)
+	for i, group := range grouped {
+		if len(group) == 0 {
+			continue
+		}
+		title := "Other changes"
+		if i < len(categories) {
+			title = categories[i].Title
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", title)
+
+		for _, mr := range group {
+			fmt.Fprintf(&b, "- %s (!%d)", mr.Title, mr.IID)
+			if mr.Author != nil {
+				fmt.Fprintf(&b, " by @%s", mr.Author.Username)
+				if !seenAuthors[mr.Author.Username] {
+					seenAuthors[mr.Author.Username] = true
+					contributors = append(contributors, "@"+mr.Author.Username)
+				}
+			}
+			if issues := closedIssues[mr.IID]; len(issues) > 0 {
+				refs := make([]string, len(issues))
+				for j, issue := range issues {
+					refs[j] = fmt.Sprintf("#%d", issue.IID)
+				}
+				fmt.Fprintf(&b, " (closes %s)", strings.Join(refs, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if len(mrs) == 0 {
+		b.WriteString("\nNo merge requests were merged in this range.\n")
+	}
+	if len(contributors) > 0 {
+		fmt.Fprintf(&b, "\n### Contributors\n\n%s\n", strings.Join(contributors, ", "))
+	}
+
+	result := map[string]interface{}{
+		"from":              from,
+		"to":                to,
+		"version":           version,
+		"source":            "merge_requests",
+		"merge_requests":    len(mrs),
+		"commits_inspected": len(commits),
+		"commits_omitted":   commitsOmitted,
+		"markdown":          b.String(),
+	}
+	return jsonResult(result)
+}
+
+// mergedMergeRequestsForCommits はコミットに関連付いたマージ済み MR を重複なくマージ順に返す
+func mergedMergeRequestsForCommits(projectID string, commits []*gitlab.Commit) ([]*gitlab.MergeRequest, error) {
+	results := make([][]*gitlab.MergeRequest, len(commits))
+	errs := make([]error, len(commits))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < defaultFetchConcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], _, errs[i] = gitlabClient.Commits.ListMergeRequestsByCommit(projectID, commits[i].ID)
+			}
+		}()
+	}
+	for i := range commits {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	seen := make(map[int]bool)
+	var mrs []*gitlab.MergeRequest
+	for i, list := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, mr := range list {
+			if mr.State != "merged" || seen[mr.IID] {
+				continue
+			}
+			seen[mr.IID] = true
+			mrs = append(mrs, mr)
+		}
+	}
+
+	sort.SliceStable(mrs, func(i, j int) bool {
+		if mrs[i].MergedAt == nil || mrs[j].MergedAt == nil {
+			return mrs[i].IID < mrs[j].IID
+		}
+		return mrs[i].MergedAt.Before(*mrs[j].MergedAt)
+	})
+	return mrs, nil
+}
+
+// generateChangelog は changelog API でリリースノートを生成する。
+// go-gitlab の GenerateChangelogData はプロジェクトパスをエスケープしないため直接リクエストを組み立てる。
+func generateChangelog(projectID, version, from, to, trailer string) (string, error) {
+	opts := &gitlab.GenerateChangelogDataOptions{
+		Version: gitlab.Ptr(version),
+		From:    gitlab.Ptr(from),
+		To:      gitlab.Ptr(to),
+	}
+	if trailer != "" {
+		opts.Trailer = gitlab.Ptr(trailer)
+	}
+
+	u := fmt.Sprintf("projects/%s/repository/changelog", gitlab.PathEscape(projectID))
+	httpReq, err := gitlabClient.NewRequest(http.MethodGet, u, opts, nil)
+	if err != nil {
+		return "", err
+	}
+
+	data := new(gitlab.ChangelogData)
+	if _, err := gitlabClient.Do(httpReq, data); err != nil {
+		return "", err
+	}
+	return data.Notes, nil
+}
+
+// hasAnyLabel はラベルのいずれかが候補に含まれるかを大文字小文字を区別せずに判定する
+func hasAnyLabel(labels gitlab.Labels, candidates []string) bool {
+	for _, l := range labels {
+		for _, c := range candidates {
+			if strings.EqualFold(l, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseAssetLinks は asset_links 引数をリリースリンクのオプションに変換する
+func parseAssetLinks(args map[string]interface{}) ([]*gitlab.CreateReleaseLinkOptions, *mcp.CallToolResult) {
+	linksArg, ok := args["asset_links"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var links []*gitlab.CreateReleaseLinkOptions
+	for i, item := range linksArg {
+		link, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, mcp.NewToolResultError(fmt.Sprintf("asset_links[%d] must be an object", i))
+		}
+
+		name := getString(link, "name", "")
+		url := getString(link, "url", "")
+		if name == "" || url == "" {
+			return nil, mcp.NewToolResultError(fmt.Sprintf("asset_links[%d] requires name and url", i))
+		}
+
+		opts := &gitlab.CreateReleaseLinkOptions{
+			Name: gitlab.Ptr(name),
+			URL:  gitlab.Ptr(url),
+		}
+		if filePath := getString(link, "filepath", ""); filePath != "" {
+			opts.FilePath = gitlab.Ptr(filePath)
+		}
+		if linkType := getString(link, "link_type", ""); linkType != "" {
+			switch gitlab.LinkTypeValue(linkType) {
+			case gitlab.OtherLinkType, gitlab.RunbookLinkType, gitlab.ImageLinkType, gitlab.PackageLinkType:
+				opts.LinkType = gitlab.Ptr(gitlab.LinkTypeValue(linkType))
+			default:
+				return nil, mcp.NewToolResultError(fmt.Sprintf("asset_links[%d]: invalid link_type: %s", i, linkType))
+			}
+		}
+		links = append(links, opts)
+	}
+	return links, nil
+}
+
+func tagSummary(t *gitlab.Tag) map[string]interface{} {
+	result := map[string]interface{}{
+		"name":      t.Name,
+		"message":   t.Message,
+		"target":    t.Target,
+		"protected": t.Protected,
+	}
+	if t.Commit != nil {
+		result["commit"] = t.Commit.ShortID
+		result["commit_title"] = t.Commit.Title
+		result["committed_date"] = t.Commit.CommittedDate
+	}
+	return result
+}
+
+// getReleaseDetails はマイルストーンを含めてリリースを取得する
+func getReleaseDetails(projectID, tagName string) (*releaseDetails, error) {
+	u := fmt.Sprintf("projects/%s/releases/%s", gitlab.PathEscape(projectID), gitlab.PathEscape(tagName))
+	httpReq, err := gitlabClient.NewRequest(http.MethodGet, u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	release := new(releaseDetails)
+	if _, err := gitlabClient.Do(httpReq, release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+func releaseSummary(r *releaseDetails) map[string]interface{} {
+	links := make([]map[string]interface{}, len(r.Assets.Links))
+	for i, l := range r.Assets.Links {
+		links[i] = map[string]interface{}{
+			"id":        l.ID,
+			"name":      l.Name,
+			"url":       l.URL,
+			"link_type": l.LinkType,
+		}
+	}
+
+	milestones := make([]string, len(r.Milestones))
+	for i, m := range r.Milestones {
+		milestones[i] = m.Title
+	}
+
+	return map[string]interface{}{
+		"tag_name":         r.TagName,
+		"name":             r.Name,
+		"description":      r.Description,
+		"created_at":       r.CreatedAt,
+		"released_at":      r.ReleasedAt,
+		"upcoming_release": r.UpcomingRelease,
+		"author":           r.Author.Username,
+		"commit":           r.Commit.ShortID,
+		"milestones":       milestones,
+		"asset_links":      links,
+		"web_url":          r.Links.Self,
+	}
+}
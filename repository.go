@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+func registerRepositoryTools(s *server.MCPServer) {
+	// リポジトリツリー取得
+	s.AddTool(
+		mcp.NewTool("list_repository_tree",
+			mcp.WithDescription("List files and directories in a GitLab repository"),
+			mcp.WithString("project_id",
+				mcp.Required(),
+				mcp.Description("Project ID or path"),
+			),
+			mcp.WithString("path",
+				mcp.Description("Directory path inside the repository (default: repository root)"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch, tag, or commit SHA (default: default branch)"),
+			),
+			mcp.WithBoolean("recursive",
+				mcp.Description("List entries of subdirectories recursively (default: false)"),
+			),
+			mcp.WithString("format",
+				mcp.Description("Output format: json (entries with type/mode/path) or tree (compact tree rendering) (default: json)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of entries per page (default: 100, max: 100)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleListRepositoryTree,
+	)
+}
+
+func handleListRepositoryTree(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID, ok := args["project_id"].(string)
+	if !ok || projectID == "" {
+		return mcp.NewToolResultError("project_id is required"), nil
+	}
+
+	treePath := strings.Trim(getString(args, "path", ""), "/")
+	ref := getString(args, "ref", "")
+	format := getString(args, "format", "json")
+	perPage := getInt(args, "per_page", 100)
+	page := getInt(args, "page", 1)
+
+	opts := &gitlab.ListTreeOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: perPage,
+			Page:    page,
+		},
+	}
+	if treePath != "" {
+		opts.Path = gitlab.Ptr(treePath)
+	}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	if recursive, ok := args["recursive"].(bool); ok {
+		opts.Recursive = gitlab.Ptr(recursive)
+	}
+
+	nodes, resp, err := gitlabClient.Repositories.ListTree(projectID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to list repository tree: %v", err)), nil
+	}
+
+	nextPage := 0
+	if resp != nil {
+		nextPage = resp.NextPage
+	}
+
+	if format == "tree" {
+		text := renderTree(treePath, nodes)
+		if nextPage > 0 {
+			text += fmt.Sprintf("\n... more entries on page %d\n", nextPage)
+		}
+		return mcp.NewToolResultText(text), nil
+	}
+
+	entries := make([]map[string]interface{}, len(nodes))
+	for i, n := range nodes {
+		entries[i] = map[string]interface{}{
+			"type": n.Type,
+			"mode": n.Mode,
+			"path": n.Path,
+			"id":   n.ID,
+		}
+	}
+
+	result := map[string]interface{}{
+		"path":      treePath,
+		"ref":       ref,
+		"entries":   entries,
+		"next_page": nextPage,
+	}
+	return jsonResult(result)
+}
+
+// treeNode はツリー表示用の中間構造
+type treeNode struct {
+	name     string
+	isDir    bool
+	children map[string]*treeNode
+}
+
+// renderTree はツリーエントリを tree コマンド風のテキストに整形する
+func renderTree(basePath string, nodes []*gitlab.TreeNode) string {
+	root := &treeNode{name: basePath, isDir: true, children: map[string]*treeNode{}}
+
+	for _, n := range nodes {
+		rel := n.Path
+		if basePath != "" {
+			rel = strings.TrimPrefix(rel, basePath+"/")
+		}
+
+		cur := root
+		parts := strings.Split(rel, "/")
+		for i, part := range parts {
+			child, ok := cur.children[part]
+			if !ok {
+				child = &treeNode{name: part, children: map[string]*treeNode{}}
+				cur.children[part] = child
+			}
+			if i < len(parts)-1 || n.Type == "tree" {
+				child.isDir = true
+			}
+			cur = child
+		}
+	}
+
+	var b strings.Builder
+	if basePath == "" {
+		b.WriteString(".\n")
+	} else {
+		b.WriteString(basePath + "/\n")
+	}
+	writeTreeChildren(&b, root, "")
+	return b.String()
+}
+
+func writeTreeChildren(b *strings.Builder, node *treeNode, prefix string) {
+	children := make([]*treeNode, 0, len(node.children))
+	for _, c := range node.children {
+		children = append(children, c)
+	}
+	// ディレクトリを先に、その後名前順
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].isDir != children[j].isDir {
+			return children[i].isDir
+		}
+		return children[i].name < children[j].name
+	})
+
+	for i, c := range children {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(children)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+
+		name := c.name
+		if c.isDir {
+			name += "/"
+		}
+		b.WriteString(prefix + connector + name + "\n")
+
+		if c.isDir {
+			writeTreeChildren(b, c, prefix+childPrefix)
+		}
+	}
+}
+
+// listAllTree はページネーションを辿ってツリーエントリをすべて取得し、実行したリクエスト数も返す。
+// ディレクトリが存在しない場合は空の結果を返す。
+func listAllTree(projectID, ref, dir string, recursive bool) ([]*gitlab.TreeNode, int, error) {
+	opts := &gitlab.ListTreeOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		Recursive: gitlab.Ptr(recursive),
+	}
+	if ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+	if dir != "" {
+		opts.Path = gitlab.Ptr(dir)
+	}
+
+	var all []*gitlab.TreeNode
+	requests := 0
+	for {
+		nodes, resp, err := gitlabClient.Repositories.ListTree(projectID, opts)
+		requests++
+		if err != nil {
+			if isNotFoundError(err) {
+				return nil, requests, nil
+			}
+			return nil, requests, err
+		}
+		all = append(all, nodes...)
+
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return all, requests, nil
+}
+
+// existingPaths は指定パスのうちリポジトリに存在するものを返す。
+// 親ディレクトリごとに非再帰のツリー取得を並行実行し、実行したリクエスト数も返す。
+func existingPaths(projectID, ref string, paths []string) (map[string]bool, int, error) {
+	var dirs []string
+	seenDirs := make(map[string]bool)
+	for _, p := range paths {
+		dir := path.Dir(p)
+		if dir == "." {
+			dir = ""
+		}
+		if !seenDirs[dir] {
+			seenDirs[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	existing := make(map[string]bool)
+	requests := 0
+
+	dirIndexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < defaultFetchConcurrency && w < len(dirs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range dirIndexes {
+				nodes, n, err := listAllTree(projectID, ref, dirs[i], false)
+
+				mu.Lock()
+				requests += n
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				for _, node := range nodes {
+					if node.Type == "blob" {
+						existing[node.Path] = true
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range dirs {
+		dirIndexes <- i
+	}
+	close(dirIndexes)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, requests, firstErr
+	}
+	return existing, requests, nil
+}
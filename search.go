@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// searchQuery は検索 API のクエリパラメータ
+type searchQuery struct {
+	gitlab.SearchOptions
+	Scope  string `url:"scope"`
+	Search string `url:"search"`
+}
+
+func registerSearchTools(s *server.MCPServer) {
+	// 検索
+	s.AddTool(
+		mcp.NewTool("search",
+			mcp.WithDescription("Search GitLab globally, within a group, or within a project. Blob hits include file path, ref, line numbers and snippets"),
+			mcp.WithString("scope",
+				mcp.Required(),
+				mcp.Description("Search scope: blobs, commits, issues, merge_requests, wiki_blobs, notes, users, milestones, projects"),
+			),
+			mcp.WithString("search",
+				mcp.Required(),
+				mcp.Description("Search query (blobs support filters such as 'filename:', 'path:' and 'extension:')"),
+			),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path to search within (required for notes)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path to search within"),
+			),
+			mcp.WithString("ref",
+				mcp.Description("Branch or tag to search (project blobs and commits only)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of results per page (default: 20)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleSearch,
+	)
+}
+
+func handleSearch(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	scope, ok := args["scope"].(string)
+	if !ok || scope == "" {
+		return mcp.NewToolResultError("scope is required"), nil
+	}
+
+	query, ok := args["search"].(string)
+	if !ok || query == "" {
+		return mcp.NewToolResultError("search is required"), nil
+	}
+
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if projectID != "" && groupID != "" {
+		return mcp.NewToolResultError("only one of project_id or group_id can be specified"), nil
+	}
+	if scope == "notes" && projectID == "" {
+		return mcp.NewToolResultError("project_id is required for the notes scope"), nil
+	}
+
+	opts := gitlab.SearchOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: getInt(args, "per_page", 20),
+			Page:    getInt(args, "page", 1),
+		},
+	}
+	if ref := getString(args, "ref", ""); ref != "" {
+		opts.Ref = gitlab.Ptr(ref)
+	}
+
+	var result []map[string]interface{}
+	switch scope {
+	case "blobs", "wiki_blobs":
+		var blobs []*gitlab.Blob
+		if err := runSearch(projectID, groupID, scope, query, opts, &blobs); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, b := range blobs {
+			result = append(result, blobHit(b))
+		}
+
+	case "commits":
+		var commits []*gitlab.Commit
+		if err := runSearch(projectID, groupID, scope, query, opts, &commits); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, c := range commits {
+			result = append(result, map[string]interface{}{
+				"id":          c.ID,
+				"short_id":    c.ShortID,
+				"title":       c.Title,
+				"author_name": c.AuthorName,
+				"created_at":  c.CreatedAt,
+				"project_id":  c.ProjectID,
+				"web_url":     c.WebURL,
+			})
+		}
+
+	case "issues":
+		var issues []*gitlab.Issue
+		if err := runSearch(projectID, groupID, scope, query, opts, &issues); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, issue := range issues {
+			result = append(result, map[string]interface{}{
+				"iid":        issue.IID,
+				"title":      issue.Title,
+				"state":      issue.State,
+				"labels":     issue.Labels,
+				"project_id": issue.ProjectID,
+				"web_url":    issue.WebURL,
+			})
+		}
+
+	case "merge_requests":
+		var mrs []*gitlab.MergeRequest
+		if err := runSearch(projectID, groupID, scope, query, opts, &mrs); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, mr := range mrs {
+			result = append(result, map[string]interface{}{
+				"iid":           mr.IID,
+				"title":         mr.Title,
+				"state":         mr.State,
+				"source_branch": mr.SourceBranch,
+				"target_branch": mr.TargetBranch,
+				"project_id":    mr.ProjectID,
+				"web_url":       mr.WebURL,
+			})
+		}
+
+	case "notes":
+		var notes []*gitlab.Note
+		if err := runSearch(projectID, groupID, scope, query, opts, &notes); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, n := range notes {
+			result = append(result, map[string]interface{}{
+				"id":            n.ID,
+				"body":          n.Body,
+				"author":        n.Author.Username,
+				"noteable_type": n.NoteableType,
+				"noteable_iid":  n.NoteableIID,
+				"created_at":    n.CreatedAt,
+			})
+		}
+
+	case "users":
+		var users []*gitlab.User
+		if err := runSearch(projectID, groupID, scope, query, opts, &users); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, u := range users {
+			result = append(result, map[string]interface{}{
+				"id":       u.ID,
+				"username": u.Username,
+				"name":     u.Name,
+				"state":    u.State,
+				"web_url":  u.WebURL,
+			})
+		}
+
+	case "milestones":
+		var milestones []*gitlab.Milestone
+		if err := runSearch(projectID, groupID, scope, query, opts, &milestones); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, m := range milestones {
+			result = append(result, map[string]interface{}{
+				"id":         m.ID,
+				"iid":        m.IID,
+				"title":      m.Title,
+				"state":      m.State,
+				"project_id": m.ProjectID,
+				"web_url":    m.WebURL,
+			})
+		}
+
+	case "projects":
+		var projects []*gitlab.Project
+		if err := runSearch(projectID, groupID, scope, query, opts, &projects); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to search: %v", err)), nil
+		}
+		for _, p := range projects {
+			result = append(result, map[string]interface{}{
+				"id":                  p.ID,
+				"path_with_namespace": p.PathWithNamespace,
+				"description":         p.Description,
+				"web_url":             p.WebURL,
+			})
+		}
+
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unsupported scope: %s", scope)), nil
+	}
+
+	return jsonResult(result)
+}
+
+// runSearch はスコープに応じてグローバル/グループ/プロジェクトの検索 API を呼び出す。
+// wiki_blobs は blobs と同じ形式で返るため、go-gitlab の型付きメソッドではなく
+// 直接リクエストを組み立てて任意の型にデコードする。
+func runSearch(projectID, groupID, scope, query string, opts gitlab.SearchOptions, result interface{}) error {
+	path := "search"
+	switch {
+	case projectID != "":
+		path = fmt.Sprintf("projects/%s/-/search", gitlab.PathEscape(projectID))
+	case groupID != "":
+		path = fmt.Sprintf("groups/%s/-/search", gitlab.PathEscape(groupID))
+	}
+
+	q := &searchQuery{SearchOptions: opts, Scope: scope, Search: query}
+	httpReq, err := gitlabClient.NewRequest(http.MethodGet, path, q, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = gitlabClient.Do(httpReq, result)
+	return err
+}
+
+// blobHit はコード検索のヒットを行番号付きのスニペットに整形する
+func blobHit(b *gitlab.Blob) map[string]interface{} {
+	lines := strings.Split(strings.TrimRight(b.Data, "\n"), "\n")
+
+	var snippet strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&snippet, "%d: %s\n", b.Startline+i, line)
+	}
+
+	return map[string]interface{}{
+		"path":       b.Path,
+		"ref":        b.Ref,
+		"project_id": b.ProjectID,
+		"start_line": b.Startline,
+		"end_line":   b.Startline + len(lines) - 1,
+		"snippet":    snippet.String(),
+	}
+}
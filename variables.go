@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/xanzy/go-gitlab"
+)
+
+// マスク対象変数の値の代わりに返す文字列
+const maskedValuePlaceholder = "[MASKED]"
+
+// revealMaskedVariables が true の場合のみマスク対象変数の値をモデルに返す
+// (GITLAB_MCP_REVEAL_MASKED_VARIABLES=true で有効化)
+var revealMaskedVariables bool
+
+func registerVariableTools(s *server.MCPServer) {
+	// CI/CD 変数一覧取得
+	s.AddTool(
+		mcp.NewTool("list_variables",
+			mcp.WithDescription("List CI/CD variables of a project or group. Values of masked variables are redacted"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithNumber("per_page",
+				mcp.Description("Number of variables per page (default: 20)"),
+			),
+			mcp.WithNumber("page",
+				mcp.Description("Page number (default: 1)"),
+			),
+		),
+		handleListVariables,
+	)
+
+	// CI/CD 変数作成
+	s.AddTool(
+		mcp.NewTool("create_variable",
+			mcp.WithDescription("Create a CI/CD variable in a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("key",
+				mcp.Required(),
+				mcp.Description("Variable key (letters, digits and '_' only)"),
+			),
+			mcp.WithString("value",
+				mcp.Required(),
+				mcp.Description("Variable value"),
+			),
+			mcp.WithString("variable_type",
+				mcp.Description("Variable type: env_var or file (default: env_var)"),
+			),
+			mcp.WithBoolean("protected",
+				mcp.Description("Only expose the variable in protected branches and tags (default: false)"),
+			),
+			mcp.WithBoolean("masked",
+				mcp.Description("Mask the variable in job logs (default: false)"),
+			),
+			mcp.WithBoolean("raw",
+				mcp.Description("Do not expand variable references in the value (default: false)"),
+			),
+			mcp.WithString("environment_scope",
+				mcp.Description("Environment scope (default: *)"),
+			),
+			mcp.WithString("description",
+				mcp.Description("Variable description"),
+			),
+		),
+		handleCreateVariable,
+	)
+
+	// CI/CD 変数更新
+	s.AddTool(
+		mcp.NewTool("update_variable",
+			mcp.WithDescription("Update a CI/CD variable in a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("key",
+				mcp.Required(),
+				mcp.Description("Variable key"),
+			),
+			mcp.WithString("value",
+				mcp.Description("New variable value"),
+			),
+			mcp.WithString("variable_type",
+				mcp.Description("Variable type: env_var or file"),
+			),
+			mcp.WithBoolean("protected",
+				mcp.Description("Only expose the variable in protected branches and tags"),
+			),
+			mcp.WithBoolean("masked",
+				mcp.Description("Mask the variable in job logs"),
+			),
+			mcp.WithBoolean("raw",
+				mcp.Description("Do not expand variable references in the value"),
+			),
+			mcp.WithString("environment_scope",
+				mcp.Description("Environment scope of the variable to update (project variables only; default: *)"),
+			),
+			mcp.WithString("description",
+				mcp.Description("Variable description"),
+			),
+		),
+		handleUpdateVariable,
+	)
+
+	// CI/CD 変数削除
+	s.AddTool(
+		mcp.NewTool("delete_variable",
+			mcp.WithDescription("Delete a CI/CD variable from a project or group"),
+			mcp.WithString("project_id",
+				mcp.Description("Project ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("group_id",
+				mcp.Description("Group ID or path (either project_id or group_id is required)"),
+			),
+			mcp.WithString("key",
+				mcp.Required(),
+				mcp.Description("Variable key"),
+			),
+			mcp.WithString("environment_scope",
+				mcp.Description("Environment scope of the variable to delete (project variables only)"),
+			),
+		),
+		handleDeleteVariable,
+	)
+}
+
+func handleListVariables(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	listOpts := gitlab.ListOptions{
+		PerPage: getInt(args, "per_page", 20),
+		Page:    getInt(args, "page", 1),
+	}
+
+	var result []map[string]interface{}
+	if projectID != "" {
+		opts := gitlab.ListProjectVariablesOptions(listOpts)
+		vars, _, err := gitlabClient.ProjectVariables.ListVariables(projectID, &opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list variables: %v", err)), nil
+		}
+		for _, v := range vars {
+			result = append(result, projectVariableSummary(v))
+		}
+	} else {
+		opts := gitlab.ListGroupVariablesOptions(listOpts)
+		vars, _, err := gitlabClient.GroupVariables.ListVariables(groupID, &opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to list variables: %v", err)), nil
+		}
+		for _, v := range vars {
+			result = append(result, groupVariableSummary(v))
+		}
+	}
+
+	return jsonResult(result)
+}
+
+func handleCreateVariable(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return mcp.NewToolResultError("key is required"), nil
+	}
+
+	value, ok := args["value"].(string)
+	if !ok {
+		return mcp.NewToolResultError("value is required"), nil
+	}
+
+	// マスク要件は API 呼び出し前に検証する
+	if getBool(args, "masked", false) {
+		if err := validateMaskedValue(value); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	if projectID != "" {
+		opts := &gitlab.CreateProjectVariableOptions{
+			Key:   gitlab.Ptr(key),
+			Value: gitlab.Ptr(value),
+		}
+		if varType := getString(args, "variable_type", ""); varType != "" {
+			opts.VariableType = gitlab.Ptr(gitlab.VariableTypeValue(varType))
+		}
+		if protected, ok := args["protected"].(bool); ok {
+			opts.Protected = gitlab.Ptr(protected)
+		}
+		if masked, ok := args["masked"].(bool); ok {
+			opts.Masked = gitlab.Ptr(masked)
+		}
+		if raw, ok := args["raw"].(bool); ok {
+			opts.Raw = gitlab.Ptr(raw)
+		}
+		if scope := getString(args, "environment_scope", ""); scope != "" {
+			opts.EnvironmentScope = gitlab.Ptr(scope)
+		}
+		if desc := getString(args, "description", ""); desc != "" {
+			opts.Description = gitlab.Ptr(desc)
+		}
+
+		v, _, err := gitlabClient.ProjectVariables.CreateVariable(projectID, opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to create variable: %v", err)), nil
+		}
+		return jsonResult(projectVariableSummary(v))
+	}
+
+	opts := &gitlab.CreateGroupVariableOptions{
+		Key:   gitlab.Ptr(key),
+		Value: gitlab.Ptr(value),
+	}
+	if varType := getString(args, "variable_type", ""); varType != "" {
+		opts.VariableType = gitlab.Ptr(gitlab.VariableTypeValue(varType))
+	}
+	if protected, ok := args["protected"].(bool); ok {
+		opts.Protected = gitlab.Ptr(protected)
+	}
+	if masked, ok := args["masked"].(bool); ok {
+		opts.Masked = gitlab.Ptr(masked)
+	}
+	if raw, ok := args["raw"].(bool); ok {
+		opts.Raw = gitlab.Ptr(raw)
+	}
+	if scope := getString(args, "environment_scope", ""); scope != "" {
+		opts.EnvironmentScope = gitlab.Ptr(scope)
+	}
+	if desc := getString(args, "description", ""); desc != "" {
+		opts.Description = gitlab.Ptr(desc)
+	}
+
+	v, _, err := gitlabClient.GroupVariables.CreateVariable(groupID, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to create variable: %v", err)), nil
+	}
+	return jsonResult(groupVariableSummary(v))
+}
+
+func handleUpdateVariable(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return mcp.NewToolResultError("key is required"), nil
+	}
+
+	value, hasValue := args["value"].(string)
+	masked, hasMasked := args["masked"].(bool)
+	scope := getString(args, "environment_scope", "")
+
+	// グループ変数の environment_scope は更新対象の選択ではなくスコープの変更になってしまう
+	if groupID != "" && scope != "" {
+		return mcp.NewToolResultError("environment_scope can only be used with project_id"), nil
+	}
+
+	if projectID != "" {
+		var filter *gitlab.VariableFilter
+		if scope != "" {
+			filter = &gitlab.VariableFilter{EnvironmentScope: scope}
+		}
+
+		// 更新前のマスク状態を確認する (値の検証と、マスク解除時の値の秘匿に使う)
+		current, _, err := gitlabClient.ProjectVariables.GetVariable(projectID, key, &gitlab.GetProjectVariableOptions{Filter: filter})
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to get variable: %v", err)), nil
+		}
+		if !hasMasked {
+			masked = current.Masked
+		}
+		if hasValue && masked {
+			if err := validateMaskedValue(value); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+		}
+
+		opts := &gitlab.UpdateProjectVariableOptions{Filter: filter}
+		if hasValue {
+			opts.Value = gitlab.Ptr(value)
+		}
+		if varType := getString(args, "variable_type", ""); varType != "" {
+			opts.VariableType = gitlab.Ptr(gitlab.VariableTypeValue(varType))
+		}
+		if protected, ok := args["protected"].(bool); ok {
+			opts.Protected = gitlab.Ptr(protected)
+		}
+		if hasMasked {
+			opts.Masked = gitlab.Ptr(masked)
+		}
+		if raw, ok := args["raw"].(bool); ok {
+			opts.Raw = gitlab.Ptr(raw)
+		}
+		if desc := getString(args, "description", ""); desc != "" {
+			opts.Description = gitlab.Ptr(desc)
+		}
+
+		v, _, err := gitlabClient.ProjectVariables.UpdateVariable(projectID, key, opts)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to update variable: %v", err)), nil
+		}
+		return jsonResult(redactUnmaskedValue(projectVariableSummary(v), current.Masked || current.Hidden))
+	}
+
+	current, _, err := gitlabClient.GroupVariables.GetVariable(groupID, key, nil)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to get variable: %v", err)), nil
+	}
+	if !hasMasked {
+		masked = current.Masked
+	}
+	if hasValue && masked {
+		if err := validateMaskedValue(value); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	opts := &gitlab.UpdateGroupVariableOptions{}
+	if hasValue {
+		opts.Value = gitlab.Ptr(value)
+	}
+	if varType := getString(args, "variable_type", ""); varType != "" {
+		opts.VariableType = gitlab.Ptr(gitlab.VariableTypeValue(varType))
+	}
+	if protected, ok := args["protected"].(bool); ok {
+		opts.Protected = gitlab.Ptr(protected)
+	}
+	if hasMasked {
+		opts.Masked = gitlab.Ptr(masked)
+	}
+	if raw, ok := args["raw"].(bool); ok {
+		opts.Raw = gitlab.Ptr(raw)
+	}
+	if desc := getString(args, "description", ""); desc != "" {
+		opts.Description = gitlab.Ptr(desc)
+	}
+
+	v, _, err := gitlabClient.GroupVariables.UpdateVariable(groupID, key, opts)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to update variable: %v", err)), nil
+	}
+	return jsonResult(redactUnmaskedValue(groupVariableSummary(v), current.Masked || current.Hidden))
+}
+
+// redactUnmaskedValue は更新前にマスクされていた変数の値を、マスク解除後も返さないようにする
+func redactUnmaskedValue(summary map[string]interface{}, wasMasked bool) map[string]interface{} {
+	if wasMasked && !revealMaskedVariables {
+		summary["value"] = maskedValuePlaceholder
+	}
+	return summary
+}
+
+func handleDeleteVariable(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.Params.Arguments
+	projectID := getString(args, "project_id", "")
+	groupID := getString(args, "group_id", "")
+	if (projectID == "") == (groupID == "") {
+		return mcp.NewToolResultError("exactly one of project_id or group_id is required"), nil
+	}
+
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return mcp.NewToolResultError("key is required"), nil
+	}
+
+	var err error
+	if projectID != "" {
+		opts := &gitlab.RemoveProjectVariableOptions{}
+		if scope := getString(args, "environment_scope", ""); scope != "" {
+			opts.Filter = &gitlab.VariableFilter{EnvironmentScope: scope}
+		}
+		_, err = gitlabClient.ProjectVariables.RemoveVariable(projectID, key, opts)
+	} else {
+		_, err = gitlabClient.GroupVariables.RemoveVariable(groupID, key)
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to delete variable: %v", err)), nil
+	}
+
+	result := map[string]interface{}{
+		"action": "deleted",
+		"key":    key,
+	}
+	return jsonResult(result)
+}
+
+func projectVariableSummary(v *gitlab.ProjectVariable) map[string]interface{} {
+	return variableSummary(v.Key, v.Value, v.VariableType, v.Protected, v.Masked, v.Hidden, v.Raw, v.EnvironmentScope, v.Description)
+}
+
+func groupVariableSummary(v *gitlab.GroupVariable) map[string]interface{} {
+	return variableSummary(v.Key, v.Value, v.VariableType, v.Protected, v.Masked, v.Hidden, v.Raw, v.EnvironmentScope, v.Description)
+}
+
+func variableSummary(key, value string, varType gitlab.VariableTypeValue, protected, masked, hidden, raw bool, scope, description string) map[string]interface{} {
+	// マスク対象の値はサーバー側で明示的に許可されない限り返さない
+	if (masked || hidden) && !revealMaskedVariables {
+		value = maskedValuePlaceholder
+	}
+
+	return map[string]interface{}{
+		"key":               key,
+		"value":             value,
+		"variable_type":     varType,
+		"protected":         protected,
+		"masked":            masked,
+		"hidden":            hidden,
+		"raw":               raw,
+		"environment_scope": scope,
+		"description":       description,
+	}
+}
+
+// validateMaskedValue は GitLab のマスク可能な値の要件を検証する
+// (8 文字以上、1 行、Base64 アルファベットと @ : . ~ - _ のみ)
+func validateMaskedValue(value string) error {
+	if len(value) < 8 {
+		return fmt.Errorf("masked variable value must be at least 8 characters long")
+	}
+	for _, c := range value {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '+' || c == '/' || c == '=' || c == '@' || c == ':' || c == '.' || c == '~' || c == '-' || c == '_':
+		default:
+			return fmt.Errorf("masked variable value contains character %q that cannot be masked; allowed are Base64 characters and @ : . ~ - _", c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestValidateMaskedValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "minimum length", value: "abcdefgh"},
+		{name: "base64", value: "dGVzdC10b2tlbg=="},
+		{name: "allowed symbols", value: "user@host:8080/a.b~c-d_e+f"},
+		{name: "empty", value: "", wantErr: true},
+		{name: "too short", value: "abcdefg", wantErr: true},
+		{name: "space", value: "abcd efgh", wantErr: true},
+		{name: "multiline", value: "abcdefgh\nijklmnop", wantErr: true},
+		{name: "trailing newline", value: "abcdefgh\n", wantErr: true},
+		{name: "disallowed symbol", value: "abcdefgh$", wantErr: true},
+		{name: "non-ascii", value: "パスワードパスワード", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMaskedValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateMaskedValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}